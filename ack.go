@@ -0,0 +1,64 @@
+package pushover
+
+import (
+	"context"
+	"time"
+)
+
+// AckInfo describes the acknowledgement of an emergency notification.
+type AckInfo struct {
+	Receipt        string
+	AcknowledgedBy string
+	AcknowledgedAt time.Time
+}
+
+// RequireAck sends an emergency message and polls its receipt every interval
+// until it's acknowledged, onAck is then called. It returns an
+// ErrReceiptExpired if the notification expires without acknowledgement.
+// When the context is done, e.g. because the incident is resolved, or the
+// polling fails, the notification is cancelled and the error returned. The
+// interval and onAck are checked before sending the message.
+func (p *Pushover) RequireAck(ctx context.Context, message *Message, recipient *Recipient, interval time.Duration, onAck func(AckInfo)) error {
+	if p.priority(message) != PriorityEmergency {
+		return ErrNotEmergency
+	}
+
+	if interval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	if onAck == nil {
+		return ErrMissingAckFunc
+	}
+
+	response, err := p.SendMessageContext(ctx, message, recipient)
+	if err != nil {
+		return err
+	}
+
+	var details *ReceiptDetails
+	err = p.PollReceiptFunc(ctx, response.Receipt, interval, func(d *ReceiptDetails) bool {
+		details = d
+		return d.Acknowledged || d.Expired
+	})
+	if err != nil {
+		// Stop paging, the acknowledgement can't be reported anymore
+		p.CancelEmergencyNotification(response.Receipt)
+		return err
+	}
+
+	if !details.Acknowledged {
+		return ErrReceiptExpired
+	}
+
+	info := AckInfo{
+		Receipt:        response.Receipt,
+		AcknowledgedBy: details.AcknowledgedBy,
+	}
+	if details.AcknowledgedAt != nil {
+		info.AcknowledgedAt = *details.AcknowledgedAt
+	}
+	onAck(info)
+
+	return nil
+}
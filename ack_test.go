@@ -0,0 +1,172 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFakeAckServer returns a server acknowledging or expiring the receipts
+// on the second poll, it counts the cancelled notifications
+func newFakeAckServer(expire bool, cancels *int32) *httptest.Server {
+	var polls int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/cancel.json"):
+			atomic.AddInt32(cancels, 1)
+			fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+		case strings.HasPrefix(r.URL.Path, "/receipts/"):
+			acknowledged, expired := 0, 0
+			if atomic.AddInt32(&polls, 1) >= 2 {
+				if expire {
+					expired = 1
+				} else {
+					acknowledged = 1
+				}
+			}
+
+			fmt.Fprintf(w, `{"status":1,"acknowledged":%d,"acknowledged_at":1393653600,"acknowledged_by":"%s",`+
+				`"last_delivered_at":0,"expired":%d,"expires_at":0,"called_back":0,"called_back_at":0,`+
+				`"request":"e95f35c2d75a100a3719b3764f0c8e47"}`, acknowledged, fakeRecipient.token, expired)
+		default:
+			w.Header().Set("X-Limit-App-Limit", "7500")
+			w.Header().Set("X-Limit-App-Remaining", "6000")
+			w.Header().Set("X-Limit-App-Reset", "1393653600")
+			fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","receipt":"KAWXTswy4cekx6vZbHBKbCKk1c1fdf"}`)
+		}
+	}))
+}
+
+// TestRequireAck tests the acknowledged emergency notification
+func TestRequireAck(t *testing.T) {
+	var cancels int32
+	ts := newFakeAckServer(false, &cancels)
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	if err := fakePushover.RequireAck(context.Background(), NewMessage("Hello"), fakeRecipient, time.Millisecond, func(AckInfo) {}); err != ErrNotEmergency {
+		t.Fatalf("expected %v, got %v", ErrNotEmergency, err)
+	}
+
+	var acks []AckInfo
+	message := NewEmergencyMessage("Server down", time.Minute, time.Hour)
+	err := fakePushover.RequireAck(context.Background(), message, fakeRecipient, time.Millisecond, func(info AckInfo) {
+		acks = append(acks, info)
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	expected := AckInfo{
+		Receipt:        "KAWXTswy4cekx6vZbHBKbCKk1c1fdf",
+		AcknowledgedBy: fakeRecipient.token,
+		AcknowledgedAt: time.Unix(1393653600, 0),
+	}
+	if len(acks) != 1 || acks[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, acks)
+	}
+
+	// The messages without a priority are sent with the app default priority
+	p := New(fakePushover.token, WithDefaultPriority(PriorityEmergency), WithEmergencyDefaults(time.Minute, time.Hour))
+	if err := p.RequireAck(context.Background(), NewMessage("Server down"), fakeRecipient, time.Millisecond, func(AckInfo) {}); err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+}
+
+// TestRequireAckErrors tests the expired and cancelled emergency
+// notifications
+func TestRequireAckErrors(t *testing.T) {
+	var cancels int32
+	ts := newFakeAckServer(true, &cancels)
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	message := NewEmergencyMessage("Server down", time.Minute, time.Hour)
+	onAck := func(AckInfo) { t.Errorf("onAck should not be called") }
+
+	if err := fakePushover.RequireAck(context.Background(), message, fakeRecipient, time.Millisecond, onAck); err != ErrReceiptExpired {
+		t.Fatalf("expected %v, got %v", ErrReceiptExpired, err)
+	}
+
+	if cancels != 0 {
+		t.Fatalf("the expired notification should not be cancelled")
+	}
+
+	// The incident is resolved while polling, the first poll doesn't expire
+	ts = newFakeAckServer(true, &cancels)
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := fakePushover.RequireAck(ctx, message, fakeRecipient, time.Hour, onAck); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if atomic.LoadInt32(&cancels) != 1 {
+		t.Fatalf("expected the notification to be cancelled")
+	}
+}
+
+// TestRequireAckInvalidArgs tests that nothing is sent with an invalid
+// interval or onAck, and that a failed polling cancels the notification
+func TestRequireAckInvalidArgs(t *testing.T) {
+	var sends, cancels int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/cancel.json"):
+			atomic.AddInt32(&cancels, 1)
+			fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+		case strings.HasPrefix(r.URL.Path, "/receipts/"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"status":0,"request":"e95f35c2d75a100a3719b3764f0c8e47","errors":["receipt not found"]}`)
+		default:
+			atomic.AddInt32(&sends, 1)
+			w.Header().Set("X-Limit-App-Limit", "7500")
+			w.Header().Set("X-Limit-App-Remaining", "6000")
+			w.Header().Set("X-Limit-App-Reset", "1393653600")
+			fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","receipt":"KAWXTswy4cekx6vZbHBKbCKk1c1fdf"}`)
+		}
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	message := NewEmergencyMessage("Server down", time.Minute, time.Hour)
+	onAck := func(AckInfo) { t.Errorf("onAck should not be called") }
+
+	tt := []struct {
+		name     string
+		interval time.Duration
+		onAck    func(AckInfo)
+		expected error
+	}{
+		{"zero interval", 0, onAck, ErrInvalidPollInterval},
+		{"negative interval", -time.Second, onAck, ErrInvalidPollInterval},
+		{"nil onAck", time.Millisecond, nil, ErrMissingAckFunc},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := fakePushover.RequireAck(context.Background(), message, fakeRecipient, tc.interval, tc.onAck); err != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	if atomic.LoadInt32(&sends) != 0 {
+		t.Fatalf("expected no message to be sent")
+	}
+
+	if err := fakePushover.RequireAck(context.Background(), message, fakeRecipient, time.Millisecond, onAck); err != ErrReceiptNotFound {
+		t.Fatalf("expected %v, got %v", ErrReceiptNotFound, err)
+	}
+
+	if atomic.LoadInt32(&cancels) != 1 {
+		t.Fatalf("expected the notification to be cancelled")
+	}
+}
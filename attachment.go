@@ -0,0 +1,237 @@
+package pushover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// Default name of the attachments added without a name.
+const defaultAttachmentName = "attachment"
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// AddAttachmentFromFS adds the named file of the file system as an
+// attachment, e.g. an image embedded with go:embed. The file is read right
+// away and its MIME type is guessed from its extension or its content.
+func (m *Message) AddAttachmentFromFS(fsys fs.FS, name string) error {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > MessageMaxAttachementByte {
+		return ErrMessageAttachementTooLarge
+	}
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > MessageMaxAttachementByte {
+		return ErrMessageAttachementTooLarge
+	}
+
+	m.setAttachment(bytes.NewReader(data), path.Base(name), attachmentType(name, data))
+	return nil
+}
+
+// AddAttachmentBytes adds the data as an attachment with the given file name
+// and MIME type, the type is guessed if empty.
+func (m *Message) AddAttachmentBytes(data []byte, filename, mimeType string) error {
+	if len(data) > MessageMaxAttachementByte {
+		return ErrMessageAttachementTooLarge
+	}
+
+	if mimeType == "" {
+		mimeType = attachmentType(filename, data)
+	}
+
+	m.setAttachment(bytes.NewReader(data), filename, mimeType)
+	return nil
+}
+
+// AddAttachmentReader adds the reader as an attachment of the given size,
+// file name and MIME type. A too large size is rejected right away, the send
+// fails with an ErrAttachmentSizeMismatch if the reader doesn't provide
+// exactly size bytes. It's the caller responsibility to close the reader.
+func (m *Message) AddAttachmentReader(r io.Reader, size int64, name, mimeType string) error {
+	if size > MessageMaxAttachementByte {
+		return ErrMessageAttachementTooLarge
+	}
+
+	m.setAttachment(r, name, mimeType)
+	m.attachmentSize = size
+	return nil
+}
+
+// AddAttachmentRange adds the length bytes of the reader starting at off as
+// an attachment, e.g. a region of a large file, without copying them. The
+// send fails with an ErrAttachmentSizeMismatch if the range goes past the
+// end of the reader.
+func (m *Message) AddAttachmentRange(ra io.ReaderAt, off, length int64, name, mimeType string) error {
+	if off < 0 || length <= 0 {
+		return ErrInvalidAttachmentRange
+	}
+
+	return m.AddAttachmentReader(io.NewSectionReader(ra, off, length), length, name, mimeType)
+}
+
+// AddAttachmentReaderWithProgress adds the reader as an attachment like
+// AddAttachmentReader, the progress func is called with the bytes of the
+// attachment sent as it's uploaded, e.g. for an upload bar. The progress
+// isn't reported with WithJSONTransport.
+func (m *Message) AddAttachmentReaderWithProgress(r io.Reader, size int64, name, mimeType string, progress func(sent, total int64)) error {
+	if err := m.AddAttachmentReader(r, size, name, mimeType); err != nil {
+		return err
+	}
+
+	m.attachmentProgress = progress
+	return nil
+}
+
+// progressReader reads a request body, reporting the progress of the
+// attachment found at the start offset.
+type progressReader struct {
+	r        io.Reader
+	read     int64
+	sent     int64
+	start    int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+
+	sent := r.read - r.start
+	switch {
+	case sent < 0:
+		sent = 0
+	case sent > r.total:
+		sent = r.total
+	}
+
+	if sent != r.sent {
+		r.sent = sent
+		r.progress(sent, r.total)
+	}
+
+	return n, err
+}
+
+// AddAttachmentFromFileHeader adds a file uploaded to a web handler as an
+// attachment. The file is read right away, its original name and content type
+// are kept, the type is guessed if the upload doesn't provide it.
+func (m *Message) AddAttachmentFromFileHeader(fh *multipart.FileHeader) error {
+	if fh.Size > MessageMaxAttachementByte {
+		return ErrMessageAttachementTooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, MessageMaxAttachementByte+1))
+	if err != nil {
+		return err
+	}
+
+	if len(data) > MessageMaxAttachementByte {
+		return ErrMessageAttachementTooLarge
+	}
+
+	name := path.Base(fh.Filename)
+	mimeType := fh.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = attachmentType(name, data)
+	}
+
+	m.setAttachment(bytes.NewReader(data), name, mimeType)
+	return nil
+}
+
+// setAttachment sets the attachment along with its file name and MIME type.
+func (m *Message) setAttachment(attachment io.Reader, name, mimeType string) {
+	m.attachment = attachment
+	m.attachmentName = name
+	m.attachmentType = mimeType
+	m.attachmentChecksum = ""
+	m.attachmentSize = 0
+	m.attachmentProgress = nil
+}
+
+// AttachmentChecksum returns the hex encoded SHA-256 of the attachment, e.g.
+// to detect an unchanged image. It's computed on the first call, the
+// attachment is then kept in memory. It returns an empty string without
+// attachment or if the attachment can't be read, the send then fails with the
+// read error.
+func (m *Message) AttachmentChecksum() string {
+	if m.attachment == nil || m.attachmentChecksum != "" {
+		return m.attachmentChecksum
+	}
+
+	data, err := m.readAttachment()
+	if err != nil {
+		m.attachment = errReader{err}
+		return ""
+	}
+
+	m.attachment = bytes.NewReader(data)
+	sum := sha256.Sum256(data)
+	m.attachmentChecksum = hex.EncodeToString(sum[:])
+	return m.attachmentChecksum
+}
+
+// errReader is a reader failing with an error.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// attachmentType returns the MIME type of an attachment from its file name
+// extension, or from its content if the extension is unknown.
+func attachmentType(name string, data []byte) string {
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		return mimeType
+	}
+
+	return http.DetectContentType(data)
+}
+
+// createAttachmentPart creates the multipart part of the attachment with its
+// file name and MIME type.
+func (m *Message) createAttachmentPart(w *multipart.Writer) (io.Writer, error) {
+	name := m.attachmentName
+	if name == "" {
+		name = defaultAttachmentName
+	}
+
+	mimeType := m.attachmentType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="attachment"; filename="%s"`, quoteEscaper.Replace(name)))
+	h.Set("Content-Type", mimeType)
+
+	return w.CreatePart(h)
+}
@@ -0,0 +1,403 @@
+package pushover
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestAddAttachmentFromFS tests the attachments from a file system
+func TestAddAttachmentFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"icons/alert.png": &fstest.MapFile{Data: []byte("\x89PNG\r\n\x1a\nfake image")},
+		"icons/alert":     &fstest.MapFile{Data: []byte("GIF89a fake image")},
+		"icons/big.png":   &fstest.MapFile{Data: make([]byte, MessageMaxAttachementByte+1)},
+	}
+
+	tt := []struct {
+		name         string
+		file         string
+		expectedName string
+		expectedType string
+		err          error
+	}{
+		{"type from extension", "icons/alert.png", "alert.png", "image/png", nil},
+		{"type from content", "icons/alert", "alert", "image/gif", nil},
+		{"too large file", "icons/big.png", "", "", ErrMessageAttachementTooLarge},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage("Hello")
+			if err := message.AddAttachmentFromFS(fsys, tc.file); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			req, err := message.multipartRequest("pToken", "rToken", "url", "")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if err := req.ParseMultipartForm(1024); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			fileHeader := req.MultipartForm.File["attachment"][0]
+			if fileHeader.Filename != tc.expectedName {
+				t.Fatalf("expected file name %q, got %q", tc.expectedName, fileHeader.Filename)
+			}
+
+			if got := fileHeader.Header.Get("Content-Type"); got != tc.expectedType {
+				t.Fatalf("expected content type %q, got %q", tc.expectedType, got)
+			}
+		})
+	}
+
+	message := NewMessage("Hello")
+	if err := message.AddAttachmentFromFS(fsys, "missing.png"); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+}
+
+// TestHalfConfiguredAttachment tests a message with an attachment name but
+// no attachment
+func TestHalfConfiguredAttachment(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	message := NewMessage("Hello")
+	message.setAttachment(nil, "alert.png", "image/png")
+
+	for _, p := range []*Pushover{fakePushover, New(fakePushover.token, WithJSONTransport())} {
+		if _, err := p.SendMessage(message, fakeRecipient); err != ErrMissingAttachement {
+			t.Fatalf("expected %v, got %v", ErrMissingAttachement, err)
+		}
+	}
+}
+
+// newFileHeader returns the file header of an uploaded file
+func newFileHeader(t *testing.T, filename, contentType string, data []byte) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="photo"; filename="%s"`, filename))
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	part.Write(data)
+	w.Close()
+
+	form, err := multipart.NewReader(body, w.Boundary()).ReadForm(1024)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	return form.File["photo"][0]
+}
+
+// TestAddAttachmentFromFileHeader tests the attachments from an upload
+func TestAddAttachmentFromFileHeader(t *testing.T) {
+	tt := []struct {
+		name         string
+		filename     string
+		contentType  string
+		data         []byte
+		expectedName string
+		expectedType string
+		err          error
+	}{
+		{"upload type", "photo.jpg", "image/jpeg", []byte("fake image"), "photo.jpg", "image/jpeg", nil},
+		{"guessed type", "photo.png", "", []byte("fake image"), "photo.png", "image/png", nil},
+		{"too large file", "big.png", "image/png", make([]byte, MessageMaxAttachementByte+1), "", "", ErrMessageAttachementTooLarge},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			fh := newFileHeader(t, tc.filename, tc.contentType, tc.data)
+			message := NewMessage("Hello")
+			if err := message.AddAttachmentFromFileHeader(fh); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			if message.attachmentName != tc.expectedName {
+				t.Fatalf("expected file name %q, got %q", tc.expectedName, message.attachmentName)
+			}
+
+			if message.attachmentType != tc.expectedType {
+				t.Fatalf("expected content type %q, got %q", tc.expectedType, message.attachmentType)
+			}
+
+			data, err := message.readAttachment()
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !bytes.Equal(data, tc.data) {
+				t.Fatalf("unexpected attachment content")
+			}
+		})
+	}
+}
+
+// TestAttachmentChecksum tests the attachment checksum
+func TestAttachmentChecksum(t *testing.T) {
+	// SHA-256 of "fake image"
+	expected := "5d2fe555eea829ac791af899610755bc8c5dc4f350bee9abbac1d2144d005fc8"
+
+	message := NewMessage("Hello")
+	if got := message.AttachmentChecksum(); got != "" {
+		t.Fatalf("expected no checksum without attachment, got %q", got)
+	}
+
+	message.AddAttachment(strings.NewReader("fake image"))
+	for i := 0; i < 2; i++ {
+		if got := message.AttachmentChecksum(); got != expected {
+			t.Fatalf("expected checksum %q, got %q", expected, got)
+		}
+	}
+
+	// The attachment can still be sent after the checksum
+	data, err := message.readAttachment()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if string(data) != "fake image" {
+		t.Fatalf("unexpected attachment content %q", data)
+	}
+
+	// A new attachment gets a new checksum
+	message.AddAttachment(strings.NewReader("other image"))
+	if got := message.AttachmentChecksum(); got == expected {
+		t.Fatalf("expected a new checksum")
+	}
+
+	// The read error is kept for the send
+	message.AddAttachment(bytes.NewReader(make([]byte, MessageMaxAttachementByte+1)))
+	if got := message.AttachmentChecksum(); got != "" {
+		t.Fatalf("expected no checksum for a too large attachment, got %q", got)
+	}
+
+	if _, err := message.readAttachment(); err != ErrMessageAttachementTooLarge {
+		t.Fatalf("expected %v, got %v", ErrMessageAttachementTooLarge, err)
+	}
+}
+
+// TestAddAttachmentBytes tests the attachments from bytes
+func TestAddAttachmentBytes(t *testing.T) {
+	tt := []struct {
+		name         string
+		data         []byte
+		filename     string
+		mimeType     string
+		expectedName string
+		expectedType string
+		err          error
+	}{
+		{"explicit type", []byte("fake image"), "chart.png", "image/webp", "chart.png", "image/webp", nil},
+		{"guessed type", []byte("fake image"), "chart.png", "", "chart.png", "image/png", nil},
+		{"no file name", []byte("GIF89a fake image"), "", "", "attachment", "image/gif", nil},
+		{"too large", make([]byte, MessageMaxAttachementByte+1), "big.png", "", "", "", ErrMessageAttachementTooLarge},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage("Hello")
+			if err := message.AddAttachmentBytes(tc.data, tc.filename, tc.mimeType); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			req, err := message.multipartRequest("pToken", "rToken", "url", "")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if err := req.ParseMultipartForm(1024); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			fileHeader := req.MultipartForm.File["attachment"][0]
+			if fileHeader.Filename != tc.expectedName {
+				t.Fatalf("expected file name %q, got %q", tc.expectedName, fileHeader.Filename)
+			}
+
+			if got := fileHeader.Header.Get("Content-Type"); got != tc.expectedType {
+				t.Fatalf("expected content type %q, got %q", tc.expectedType, got)
+			}
+		})
+	}
+}
+
+// TestAddAttachmentReader tests the attachments from a reader of a known size
+func TestAddAttachmentReader(t *testing.T) {
+	tt := []struct {
+		name        string
+		data        string
+		size        int64
+		addErr      error
+		expectedErr error
+	}{
+		{"exact size", "fake image", 10, nil, nil},
+		{"shorter reader", "fake", 10, nil, ErrAttachmentSizeMismatch},
+		{"longer reader", "fake image !", 10, nil, ErrAttachmentSizeMismatch},
+		{"too large size", "", MessageMaxAttachementByte + 1, ErrMessageAttachementTooLarge, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage("Hello")
+			err := message.AddAttachmentReader(strings.NewReader(tc.data), tc.size, "image.png", "image/png")
+			if err != tc.addErr {
+				t.Fatalf("expected %v, got %v", tc.addErr, err)
+			}
+
+			if tc.addErr != nil {
+				return
+			}
+
+			req, err := message.multipartRequest("pToken", "rToken", "url", "")
+			if err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if err == nil && req.ContentLength <= tc.size {
+				t.Fatalf("expected the content length to be set, got %d", req.ContentLength)
+			}
+		})
+	}
+}
+
+// TestAddAttachmentRange tests the attachment of a region of a reader
+func TestAddAttachmentRange(t *testing.T) {
+	ra := strings.NewReader("header fake image footer")
+
+	tt := []struct {
+		name        string
+		off         int64
+		length      int64
+		addErr      error
+		expected    string
+		expectedErr error
+	}{
+		{"region", 7, 10, nil, "fake image", nil},
+		{"whole reader", 0, 24, nil, "header fake image footer", nil},
+		{"past the end", 18, 10, nil, "", ErrAttachmentSizeMismatch},
+		{"negative offset", -1, 10, ErrInvalidAttachmentRange, "", nil},
+		{"zero length", 0, 0, ErrInvalidAttachmentRange, "", nil},
+		{"negative length", 0, -1, ErrInvalidAttachmentRange, "", nil},
+		{"too large length", 0, MessageMaxAttachementByte + 1, ErrMessageAttachementTooLarge, "", nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage("Hello")
+			err := message.AddAttachmentRange(ra, tc.off, tc.length, "image.png", "image/png")
+			if err != tc.addErr {
+				t.Fatalf("expected %v, got %v", tc.addErr, err)
+			}
+
+			if tc.addErr != nil {
+				return
+			}
+
+			req, err := message.multipartRequest("pToken", "rToken", "url", "")
+			if err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if err := req.ParseMultipartForm(MessageMaxAttachementByte); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			f, _, err := req.FormFile("attachment")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			got, _ := io.ReadAll(f)
+			if string(got) != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestAddAttachmentReaderWithProgress tests the progress reported as the
+// attachment is uploaded
+func TestAddAttachmentReaderWithProgress(t *testing.T) {
+	data := strings.Repeat("x", 100000)
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f, _, err := r.FormFile("attachment"); err == nil {
+			b, _ := io.ReadAll(f)
+			received = string(b)
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	var calls [][2]int64
+	message := NewMessage("Hello")
+	err := message.AddAttachmentReaderWithProgress(strings.NewReader(data), int64(len(data)), "screenshot.png", "image/png", func(sent, total int64) {
+		calls = append(calls, [2]int64{sent, total})
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := fakePushover.SendMessage(message, fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if received != data {
+		t.Fatalf("expected the attachment to be received, got %d bytes", len(received))
+	}
+
+	if len(calls) < 2 {
+		t.Fatalf("expected the progress to be reported many times, got %v", calls)
+	}
+
+	for i, call := range calls {
+		if call[1] != int64(len(data)) || (i > 0 && call[0] <= calls[i-1][0]) {
+			t.Fatalf("unexpected progress %v", calls)
+		}
+	}
+
+	if last := calls[len(calls)-1]; last[0] != last[1] {
+		t.Fatalf("expected the progress to be complete, got %v", last)
+	}
+}
@@ -0,0 +1,213 @@
+package pushover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultMaxConcurrency is the default number of concurrent sends of a bulk
+// send.
+const defaultMaxConcurrency = 4
+
+// BulkResult is the result of a message sent to one of the recipients of a
+// bulk send.
+type BulkResult struct {
+	Recipient *Recipient
+	Response  *Response
+	Err       error
+}
+
+// BulkSummary sums up the results of a bulk send.
+type BulkSummary struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	// Failures maps the recipient tokens of the failed sends to their error,
+	// a recipient listed many times appears only once with its last error.
+	Failures map[string]error
+	// Receipts maps the recipient tokens to the receipts of the emergency
+	// messages, to poll them.
+	Receipts map[string]string
+}
+
+// String represents a printable form of the summary.
+func (s BulkSummary) String() string {
+	return fmt.Sprintf("Delivered to %d/%d recipients", s.Succeeded, s.Total)
+}
+
+// Err returns a BulkError gathering the errors of the failed sends, or nil
+// if all the sends succeeded. The original errors are kept so they can be
+// matched with errors.Is and errors.As.
+func (s BulkSummary) Err() error {
+	if len(s.Failures) == 0 {
+		return nil
+	}
+
+	tokens := make([]string, 0, len(s.Failures))
+	for token := range s.Failures {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	ret := make(BulkError, 0, len(tokens))
+	for _, token := range tokens {
+		ret = append(ret, s.Failures[token])
+	}
+
+	return ret
+}
+
+// BulkError gathers the errors of a bulk send.
+type BulkError []error
+
+// Error represents the errors as a string.
+func (e BulkError) Error() string {
+	errs := make([]string, len(e))
+	for i, err := range e {
+		errs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("pushover: %d sends failed:\n%s", len(e), strings.Join(errs, "\n"))
+}
+
+// Unwrap returns the errors of the bulk send.
+func (e BulkError) Unwrap() []error {
+	return e
+}
+
+// SendToMany sends a message to many recipients, up to 4 at once unless set
+// with WithMaxConcurrency. It returns the result of each send in the
+// recipients order along with a summary of the results. The message
+// attachment is read once and sent to each recipient.
+func (p *Pushover) SendToMany(message *Message, recipients []*Recipient) ([]*BulkResult, *BulkSummary) {
+	return p.SendToManyContext(context.Background(), message, recipients)
+}
+
+// SendToManyContext sends a message to many recipients like SendToMany, the
+// remaining sends are aborted when the context is done.
+func (p *Pushover) SendToManyContext(ctx context.Context, message *Message, recipients []*Recipient) ([]*BulkResult, *BulkSummary) {
+	// The attachment reader can only be read once, it's read in memory to be
+	// sent to all the recipients
+	var attachment []byte
+	var attachmentErr error
+	if message.attachment != nil {
+		attachment, attachmentErr = message.readAttachment()
+	}
+
+	return p.sendToMany(ctx, recipients, func(ctx context.Context, recipient *Recipient) (*Response, error) {
+		if attachmentErr != nil {
+			return nil, attachmentErr
+		}
+
+		m := message
+		if attachment != nil {
+			withAttachment := *message
+			withAttachment.attachment = bytes.NewReader(attachment)
+			m = &withAttachment
+		}
+
+		return p.SendMessageContext(ctx, m, recipient)
+	})
+}
+
+// SendToManyFunc sends a message built for each recipient, e.g. with the
+// recipient name in the title, like SendToManyContext. The build func is
+// called concurrently, up to the max concurrency.
+func (p *Pushover) SendToManyFunc(ctx context.Context, recipients []*Recipient, build func(*Recipient) *Message) ([]*BulkResult, *BulkSummary) {
+	return p.sendToMany(ctx, recipients, func(ctx context.Context, recipient *Recipient) (*Response, error) {
+		message := build(recipient)
+		if message == nil {
+			return nil, ErrMessageEmpty
+		}
+
+		return p.SendMessageContext(ctx, message, recipient)
+	})
+}
+
+// sendToMany calls send for each recipient, up to the max concurrency at
+// once, and sums up the results.
+func (p *Pushover) sendToMany(ctx context.Context, recipients []*Recipient, send func(context.Context, *Recipient) (*Response, error)) ([]*BulkResult, *BulkSummary) {
+	// The retries are bounded for the whole bulk send
+	if p.batchRetryBudget > 0 {
+		ctx = withRetryBudget(ctx, p.batchRetryBudget)
+	}
+
+	// The remaining sends are aborted on CancelAll
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer p.track(cancel)()
+
+	concurrency := p.maxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultMaxConcurrency
+	}
+
+	// Send to up to concurrency recipients at once
+	results := make([]*BulkResult, len(recipients))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, recipient := range recipients {
+		// Send to the default recipient if none is given
+		if recipient == nil {
+			recipient = p.defaultRecipient
+			if recipient == nil {
+				results[i] = &BulkResult{Err: ErrEmptyRecipientToken}
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			results[i] = &BulkResult{Recipient: recipient, Err: ctx.Err()}
+			continue
+		case sem <- struct{}{}:
+		}
+
+		// The context may be done while waiting for a free slot
+		if err := ctx.Err(); err != nil {
+			results[i] = &BulkResult{Recipient: recipient, Err: err}
+			<-sem
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, recipient *Recipient) {
+			defer wg.Done()
+			resp, err := send(ctx, recipient)
+			results[i] = &BulkResult{Recipient: recipient, Response: resp, Err: err}
+			<-sem
+		}(i, recipient)
+	}
+	wg.Wait()
+
+	summary := &BulkSummary{
+		Total:    len(recipients),
+		Failures: map[string]error{},
+		Receipts: map[string]string{},
+	}
+
+	for _, result := range results {
+		// The missing recipients are reported with an empty token
+		var token string
+		if result.Recipient != nil {
+			token = result.Recipient.token
+		}
+
+		if result.Err != nil {
+			summary.Failed++
+			summary.Failures[token] = result.Err
+			continue
+		}
+
+		summary.Succeeded++
+		if result.Response.Receipt != "" {
+			summary.Receipts[token] = result.Response.Receipt
+		}
+	}
+
+	return results, summary
+}
@@ -0,0 +1,388 @@
+package pushover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Recipient rejected by the fake bulk server
+var fakeInvalidRecipient = NewRecipient("aznej3rKEVAvPUxu9vvNnqpmZpokzF")
+
+// newFakeBulkServer returns a server rejecting the fakeInvalidRecipient
+func newFakeBulkServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("user") == fakeInvalidRecipient.token {
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+}
+
+// TestSendToMany tests the results and the summary of a bulk send
+func TestSendToMany(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	recipients := []*Recipient{fakeRecipient, fakeInvalidRecipient, fakeRecipient}
+	results, summary := fakePushover.SendToMany(NewMessage("Hello"), recipients)
+
+	if len(results) != len(recipients) {
+		t.Fatalf("expected %d results, got %d", len(recipients), len(results))
+	}
+
+	for i, result := range results {
+		if result.Recipient != recipients[i] {
+			t.Fatalf("unexpected recipient for result %d", i)
+		}
+
+		if gotErr := result.Err != nil; gotErr != (i == 1) {
+			t.Fatalf("unexpected error for result %d: %v", i, result.Err)
+		}
+	}
+
+	expected := &BulkSummary{
+		Total:     3,
+		Succeeded: 2,
+		Failed:    1,
+		Failures: map[string]error{
+			fakeInvalidRecipient.token: Errors{"user identifier is invalid"},
+		},
+		Receipts: map[string]string{},
+	}
+
+	if !reflect.DeepEqual(summary, expected) {
+		t.Fatalf("unexpected summary\nExpected:\t%v\nGot:\t%v", expected, summary)
+	}
+
+	if got := summary.String(); got != "Delivered to 2/3 recipients" {
+		t.Fatalf("unexpected summary string: %s", got)
+	}
+}
+
+// TestSendToManyDefaultRecipient tests the nil recipients sent to the app
+// default recipient, or reported as missing without one
+func TestSendToManyDefaultRecipient(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	tt := []struct {
+		name              string
+		opts              []Option
+		expectedRecipient *Recipient
+		expectedErr       error
+	}{
+		{"with default", []Option{WithDefaultRecipient(fakeRecipient)}, fakeRecipient, nil},
+		{"without default", nil, nil, ErrEmptyRecipientToken},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			results, summary := p.SendToMany(NewMessage("Hello"), []*Recipient{nil})
+
+			if results[0].Recipient != tc.expectedRecipient {
+				t.Fatalf("expected recipient %v, got %v", tc.expectedRecipient, results[0].Recipient)
+			}
+
+			if results[0].Err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, results[0].Err)
+			}
+
+			if failed := tc.expectedErr != nil; (summary.Failed == 1) != failed || summary.Total != 1 {
+				t.Fatalf("unexpected summary %+v", summary)
+			}
+		})
+	}
+}
+
+// TestSendToManyWithAttachment tests that the attachment is sent to all the
+// recipients
+func TestSendToManyWithAttachment(t *testing.T) {
+	attachment := []byte("GIF89a fake image")
+	var sizes []int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := int64(-1)
+		if err := r.ParseMultipartForm(1024); err == nil {
+			if files := r.MultipartForm.File["attachment"]; len(files) == 1 {
+				size = files[0].Size
+			}
+		}
+		sizes = append(sizes, size)
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	message := NewMessage("Hello")
+	message.AddAttachment(bytes.NewReader(attachment))
+
+	recipients := []*Recipient{fakeRecipient, fakeRecipient, fakeRecipient}
+	_, summary := fakePushover.SendToMany(message, recipients)
+	if summary.Succeeded != len(recipients) {
+		t.Fatalf("expected %d successful sends, got %v", len(recipients), summary)
+	}
+
+	size := int64(len(attachment))
+	expected := []int64{size, size, size}
+	if !reflect.DeepEqual(sizes, expected) {
+		t.Fatalf("expected attachment sizes %v, got %v", expected, sizes)
+	}
+}
+
+// TestSendToManyWithTooLargeAttachment tests that all the sends fail with a
+// too large attachment
+func TestSendToManyWithTooLargeAttachment(t *testing.T) {
+	message := NewMessage("Hello")
+	message.AddAttachment(bytes.NewReader(make([]byte, MessageMaxAttachementByte+1)))
+
+	results, summary := fakePushover.SendToMany(message, []*Recipient{fakeRecipient, fakeRecipient})
+	for _, result := range results {
+		if result.Err != ErrMessageAttachementTooLarge {
+			t.Fatalf("expected %v, got %v", ErrMessageAttachementTooLarge, result.Err)
+		}
+	}
+
+	if summary.Failed != 2 {
+		t.Fatalf("expected 2 failures, got %d", summary.Failed)
+	}
+}
+
+// TestSendToManyEmergency tests that the receipts of an emergency bulk send
+// are collected
+func TestSendToManyEmergency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintf(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","receipt":"receipt-%s"}`, r.FormValue("user"))
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	recipients := []*Recipient{fakeRecipient, fakeInvalidRecipient}
+	_, summary := fakePushover.SendToMany(NewEmergencyMessage("Hello", time.Minute, time.Hour), recipients)
+
+	expected := map[string]string{
+		fakeRecipient.token:        "receipt-" + fakeRecipient.token,
+		fakeInvalidRecipient.token: "receipt-" + fakeInvalidRecipient.token,
+	}
+
+	if !reflect.DeepEqual(summary.Receipts, expected) {
+		t.Fatalf("expected receipts %v, got %v", expected, summary.Receipts)
+	}
+}
+
+// TestSendToManyValidationErrors tests that the validation errors are kept
+// through the bulk results and summary
+func TestSendToManyValidationErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		message *Message
+		err     error
+	}{
+		{"message too long", NewMessage(strings.Repeat("a", MessageMaxLength+1)), ErrMessageTooLong},
+		{"title too long", NewMessageWithTitle("Hello", strings.Repeat("a", MessageTitleMaxLength+1)), ErrMessageTitleTooLong},
+		{"URL too long", &Message{Message: "Hello", URL: strings.Repeat("a", MessageURLMaxLength+1)}, ErrMessageURLTooLong},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			recipients := []*Recipient{fakeRecipient, fakeInvalidRecipient}
+			results, summary := fakePushover.SendToMany(tc.message, recipients)
+			for _, result := range results {
+				if result.Err != tc.err {
+					t.Fatalf("expected %v, got %v", tc.err, result.Err)
+				}
+			}
+
+			err := summary.Err()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected the summary error to match %v, got %v", tc.err, err)
+			}
+
+			var bulkErr BulkError
+			if !errors.As(err, &bulkErr) || len(bulkErr) != len(recipients) {
+				t.Fatalf("expected a BulkError with %d errors, got %v", len(recipients), err)
+			}
+		})
+	}
+
+	// No error when all the sends succeed
+	if err := (BulkSummary{Total: 1, Succeeded: 1}).Err(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// TestSendToManyConcurrency tests the number of concurrent sends of a bulk
+// send
+func TestSendToManyConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	recipients := make([]*Recipient, 12)
+	for i := range recipients {
+		recipients[i] = fakeRecipient
+	}
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		expected int32
+	}{
+		{"default", nil, defaultMaxConcurrency},
+		{"sequential", []Option{WithMaxConcurrency(1)}, 1},
+		{"concurrent", []Option{WithMaxConcurrency(6)}, 6},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&maxInFlight, 0)
+			p := New(fakePushover.token, tc.opts...)
+			results, summary := p.SendToMany(NewMessage("Hello"), recipients)
+			if summary.Succeeded != len(recipients) || len(results) != len(recipients) {
+				t.Fatalf("unexpected summary %+v", summary)
+			}
+
+			got := atomic.LoadInt32(&maxInFlight)
+			if got > tc.expected || (tc.expected > 1 && got < 2) {
+				t.Fatalf("expected up to %d concurrent sends, got %d", tc.expected, got)
+			}
+		})
+	}
+
+	// The remaining sends are aborted when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, summary := fakePushover.SendToManyContext(ctx, NewMessage("Hello"), recipients)
+	if summary.Failed != len(recipients) || results[0].Err != context.Canceled {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+}
+
+// TestSendToManyCancelAll tests that CancelAll stops a bulk send, the
+// remaining recipients are not sent the message
+func TestSendToManyCancelAll(t *testing.T) {
+	var p *Pushover
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 2 {
+			p.CancelAll()
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	p = New(fakePushover.token, WithMaxConcurrency(1))
+	recipients := make([]*Recipient, 5)
+	for i := range recipients {
+		recipients[i] = fakeRecipient
+	}
+
+	results, summary := p.SendToMany(NewMessage("Hello"), recipients)
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected 2 requests, got %d", got)
+	}
+
+	if summary.Succeeded > 2 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+
+	for _, result := range results[2:] {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatalf("expected %v, got %v", context.Canceled, result.Err)
+		}
+	}
+
+	// The app is still usable afterwards
+	if _, summary := p.SendToMany(NewMessage("Hello"), recipients[:1]); summary.Succeeded != 1 {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+}
+
+// TestSendToManyFunc tests the messages built for each recipient
+func TestSendToManyFunc(t *testing.T) {
+	var mu sync.Mutex
+	sent := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent[r.FormValue("user")] = r.FormValue("title") + " " + r.FormValue("device")
+		mu.Unlock()
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	names := map[string]string{fakeRecipient.token: "Alice", fakeInvalidRecipient.token: "Bob"}
+	skipped := NewRecipient("bznej3rKEVAvPUxu9vvNnqpmZpokzF")
+	recipients := []*Recipient{fakeRecipient, fakeInvalidRecipient, skipped}
+
+	results, summary := fakePushover.SendToManyFunc(context.Background(), recipients, func(r *Recipient) *Message {
+		name, ok := names[r.token]
+		if !ok {
+			return nil
+		}
+
+		m := NewMessageWithTitle("Disk full", "Hi "+name)
+		m.DeviceName = strings.ToLower(name)
+		return m
+	})
+
+	expected := map[string]string{
+		fakeRecipient.token:        "Hi Alice alice",
+		fakeInvalidRecipient.token: "Hi Bob bob",
+	}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Fatalf("expected %v, got %v", expected, sent)
+	}
+
+	if summary.Succeeded != 2 || summary.Failed != 1 || results[2].Err != ErrMessageEmpty {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+}
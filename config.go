@@ -0,0 +1,62 @@
+package pushover
+
+import "time"
+
+// Config represents the settings of an app, e.g. loaded from a configuration
+// file. The zero values keep the defaults.
+type Config struct {
+	// AppToken is the API token of the app, it's required.
+	AppToken string `json:"app_token" yaml:"app_token"`
+	// DefaultUser is the user key of the recipient of the messages sent
+	// with a nil recipient.
+	DefaultUser string `json:"default_user" yaml:"default_user"`
+	// Timeout bounds the duration of each request.
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// MaxRetries is the number of retries of the requests failing with a
+	// server or network error, the first one after RetryBackoff.
+	MaxRetries   int           `json:"max_retries" yaml:"max_retries"`
+	RetryBackoff time.Duration `json:"retry_backoff" yaml:"retry_backoff"`
+	// DefaultTitle is the title of the messages sent without one.
+	DefaultTitle string `json:"default_title" yaml:"default_title"`
+	// StrictValidation enables the device and sound checks.
+	StrictValidation bool `json:"strict_validation" yaml:"strict_validation"`
+}
+
+// NewFromConfig returns a new app configured from the config, it fails if
+// the app token or the default user key is malformed. More options can be
+// given for the settings the config doesn't cover.
+func NewFromConfig(cfg Config, opts ...Option) (*Pushover, error) {
+	if cfg.AppToken == "" {
+		return nil, ErrEmptyToken
+	}
+
+	if !ValidToken(cfg.AppToken) {
+		return nil, ErrMalformedToken
+	}
+
+	var cfgOpts []Option
+	if cfg.DefaultUser != "" {
+		if !ValidUserKey(cfg.DefaultUser) {
+			return nil, ErrMalformedUserKey
+		}
+		cfgOpts = append(cfgOpts, WithDefaultRecipient(NewRecipient(cfg.DefaultUser)))
+	}
+
+	if cfg.Timeout > 0 {
+		cfgOpts = append(cfgOpts, WithTimeout(cfg.Timeout))
+	}
+
+	if cfg.MaxRetries > 0 {
+		cfgOpts = append(cfgOpts, WithRetries(cfg.MaxRetries, cfg.RetryBackoff))
+	}
+
+	if cfg.DefaultTitle != "" {
+		cfgOpts = append(cfgOpts, WithDefaultTitle(cfg.DefaultTitle))
+	}
+
+	if cfg.StrictValidation {
+		cfgOpts = append(cfgOpts, WithStrictValidation())
+	}
+
+	return New(cfg.AppToken, append(cfgOpts, opts...)...), nil
+}
@@ -0,0 +1,87 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestNewFromConfig tests the app configured from a config
+func TestNewFromConfig(t *testing.T) {
+	tt := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{"empty token", Config{}, ErrEmptyToken},
+		{"malformed token", Config{AppToken: "uQiRzpo4DXghDmr9QzzfQu27cmVRs"}, ErrMalformedToken},
+		{"malformed user", Config{AppToken: fakePushover.token, DefaultUser: "gznej3rKEVAvPUxu9vvNnqpmZpokz"}, ErrMalformedUserKey},
+		{"valid config", Config{AppToken: fakePushover.token, DefaultUser: fakeRecipient.token}, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewFromConfig(tc.cfg); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestConfigDefaults tests the settings of an app configured from a config
+func TestConfigDefaults(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	p, err := NewFromConfig(Config{
+		AppToken:     fakePushover.token,
+		DefaultUser:  fakeRecipient.token,
+		MaxRetries:   2,
+		RetryBackoff: time.Second,
+		DefaultTitle: "My app",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if p.maxRetries != 2 || p.retryBackoff != time.Second {
+		t.Fatalf("unexpected retries %d after %s", p.maxRetries, p.retryBackoff)
+	}
+
+	if got := p.prepareMessage(NewMessage("Hello")).Title; got != "My app" {
+		t.Fatalf("expected the default title, got %q", got)
+	}
+
+	if _, err := p.SendMessage(NewMessage("Hello"), nil); err != nil {
+		t.Fatalf("expected no error sending to the default user, got %q", err)
+	}
+
+	if _, err := fakePushover.SendMessage(NewMessage("Hello"), nil); err != ErrEmptyRecipientToken {
+		t.Fatalf("expected %v without default user, got %v", ErrEmptyRecipientToken, err)
+	}
+}
+
+// TestTimeout tests that the slow requests time out
+func TestTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	APIEndpoint = ts.URL
+	p, err := NewFromConfig(Config{AppToken: fakePushover.token, Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	_, err = p.SendMessage(NewMessage("Hello"), fakeRecipient)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,20 @@
+package pushover
+
+import "context"
+
+// correlationIDKey is the context key of the correlation id.
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a context carrying a correlation id, e.g.
+// assigned upstream by a distributed tracing. The messages sent with this
+// context get the id in their response, it's never sent to the API.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation id of the context, or an
+// empty string if there is none.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,43 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCorrelationID tests that the correlation id is returned in the
+// response without being sent
+func TestCorrelationID(t *testing.T) {
+	id := "trace-4bf92f3577b34da6"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		for key, values := range r.Form {
+			for _, value := range values {
+				if value == id {
+					t.Errorf("the correlation id should not be sent, found in %q", key)
+				}
+			}
+		}
+
+		fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	ctx := ContextWithCorrelationID(context.Background(), id)
+	response, err := fakePushover.SendMessageContext(ctx, NewMessage("Hello"), fakeRecipient)
+	if err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	if response == nil || response.CorrelationID != id {
+		t.Fatalf("expected the correlation id %q in the response, got %+v", id, response)
+	}
+
+	if got := CorrelationIDFromContext(context.Background()); got != "" {
+		t.Fatalf("expected no correlation id, got %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package pushover
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DedupeStore records the messages recently sent to swallow their
+// duplicates, see WithDedupeWindow. A shared store, e.g. backed by Redis,
+// dedupes the messages across processes.
+type DedupeStore interface {
+	// Add records the key for the given duration, it returns false if the
+	// key is already recorded.
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Remove forgets the key, e.g. when the message couldn't be sent.
+	Remove(ctx context.Context, key string) error
+}
+
+// memoryDedupeStore is the default in-memory dedupe store.
+type memoryDedupeStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func (s *memoryDedupeStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.expires == nil {
+		s.expires = map[string]time.Time{}
+	}
+
+	// Forget the expired keys
+	for k, expire := range s.expires {
+		if !now.Before(expire) {
+			delete(s.expires, k)
+		}
+	}
+
+	if _, ok := s.expires[key]; ok {
+		return false, nil
+	}
+
+	s.expires[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (s *memoryDedupeStore) Remove(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.expires, key)
+	return nil
+}
+
+// dedupeKey returns the hash identifying the duplicates of a message, the
+// messages sent to other devices of the recipient are not duplicates.
+func dedupeKey(message *Message, recipient *Recipient) string {
+	h := sha256.New()
+	fields := []string{recipient.token, message.Title, message.Message, strconv.Itoa(message.Priority)}
+	for _, s := range append(fields, message.deviceNames()...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dedupe records the message, it returns true if it's a duplicate of a
+// message sent within the dedupe window. The key is returned to be removed
+// if the send fails.
+func (p *Pushover) dedupe(ctx context.Context, message *Message, recipient *Recipient) (key string, duplicate bool, err error) {
+	if p.dedupeWindow <= 0 {
+		return "", false, nil
+	}
+
+	key = dedupeKey(message, recipient)
+	added, err := p.dedupeStore.Add(ctx, key, p.dedupeWindow)
+	if err != nil {
+		return "", false, err
+	}
+
+	return key, !added, nil
+}
@@ -0,0 +1,125 @@
+package pushover
+
+import (
+	"bytes"
+	"context"
+	"sort"
+)
+
+// Device represents the name of a device of a recipient, validated when
+// created with NewDevice.
+type Device string
+
+// NewDevice returns the device with the given name, or an
+// ErrInvalidDeviceName if the name isn't valid.
+func NewDevice(name string) (Device, error) {
+	if !deviceNameRegexp.MatchString(name) {
+		return "", ErrInvalidDeviceName
+	}
+
+	return Device(name), nil
+}
+
+// DeviceResult is the result of a message sent to one of the devices of a
+// recipient.
+type DeviceResult struct {
+	Device   Device
+	Response *Response
+	Err      error
+}
+
+// SendWithDeviceSounds sends the message to each device of the recipient
+// with its own sound, e.g. loud on a phone and silent on a tablet, one send
+// per device. It returns the result of each send sorted by device, along
+// with a BulkError gathering the errors of the failed sends.
+func (p *Pushover) SendWithDeviceSounds(ctx context.Context, message *Message, recipient *Recipient, sounds map[Device]string) ([]*DeviceResult, error) {
+	devices := make([]Device, 0, len(sounds))
+	for device := range sounds {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i] < devices[j] })
+
+	// The attachment reader can only be read once, it's read in memory to be
+	// sent to all the devices
+	var attachment []byte
+	if message.attachment != nil {
+		data, err := message.readAttachment()
+		if err != nil {
+			return nil, err
+		}
+		attachment = data
+	}
+
+	results := make([]*DeviceResult, 0, len(devices))
+	var errs BulkError
+	for _, device := range devices {
+		m := *message
+		m.DeviceName = string(device)
+		m.Devices = nil
+		m.Sound = sounds[device]
+		if attachment != nil {
+			m.attachment = bytes.NewReader(attachment)
+		}
+
+		resp, err := p.SendMessageContext(ctx, &m, recipient)
+		results = append(results, &DeviceResult{Device: device, Response: resp, Err: err})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, errs
+	}
+
+	return results, nil
+}
+
+// cacheDevices keeps the devices of a validated recipient.
+func (p *Pushover) cacheDevices(recipient *Recipient, devices []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.devices == nil {
+		p.devices = map[string][]string{}
+	}
+
+	p.devices[recipient.token] = append([]string(nil), devices...)
+}
+
+// validateDevice returns an ErrUnknownDevice if the message targets a device
+// missing from the cached devices of the recipient. The messages to the
+// recipients never validated are not checked.
+func (p *Pushover) validateDevice(message *Message, recipient *Recipient) error {
+	names := message.deviceNames()
+	if !p.strictValidation || len(names) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	devices, ok := p.devices[recipient.token]
+	if !ok {
+		return nil
+	}
+
+	for _, name := range names {
+		if !contains(devices, name) {
+			return ErrUnknownDevice
+		}
+	}
+
+	return nil
+}
+
+// contains returns true if the list contains the string.
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
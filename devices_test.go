@@ -0,0 +1,124 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNewDevice tests the devices validated at creation
+func TestNewDevice(t *testing.T) {
+	tt := []struct {
+		name string
+		err  error
+	}{
+		{"droid-2", nil},
+		{"kiosk_display", nil},
+		{"", ErrInvalidDeviceName},
+		{"with space", ErrInvalidDeviceName},
+		{"a-device-name-longer-than-25", ErrInvalidDeviceName},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			device, err := NewDevice(tc.name)
+			if err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if err == nil && string(device) != tc.name {
+				t.Fatalf("expected device %q, got %q", tc.name, device)
+			}
+		})
+	}
+}
+
+// TestMessageDevices tests the devices targeted by a message
+func TestMessageDevices(t *testing.T) {
+	tt := []struct {
+		name       string
+		deviceName string
+		devices    []Device
+		expected   string
+		err        error
+	}{
+		{"all devices", "", nil, "", nil},
+		{"devices", "", []Device{"iphone", "desktop"}, "iphone,desktop", nil},
+		{"device name and devices", "iphone", []Device{"desktop"}, "iphone,desktop", nil},
+		{"invalid converted device", "", []Device{"with space"}, "", ErrInvalidDeviceName},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage("Hello")
+			message.DeviceName = tc.deviceName
+			message.Devices = tc.devices
+			if err := message.validate(); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			if got := message.toMap("pToken", "rToken")["device"]; got != tc.expected {
+				t.Fatalf("expected device %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestSendWithDeviceSounds tests the message sent to each device with its
+// own sound
+func TestSendWithDeviceSounds(t *testing.T) {
+	var mu sync.Mutex
+	sent := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("device") == "broken" {
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["device name is not valid for user"]}`)
+			return
+		}
+
+		mu.Lock()
+		sent[r.FormValue("device")] = r.FormValue("sound") + " " + r.FormValue("message")
+		mu.Unlock()
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	// The dedupe doesn't swallow the messages sent to the other devices
+	p := New(fakePushover.token, WithDedupeWindow(time.Minute))
+	sounds := map[Device]string{"phone": SoundSiren, "tablet": SoundNone, "broken": SoundCosmic}
+	results, err := p.SendWithDeviceSounds(context.Background(), NewMessage("Disk full"), fakeRecipient, sounds)
+
+	var bulkErr BulkError
+	if !errors.As(err, &bulkErr) || len(bulkErr) != 1 {
+		t.Fatalf("expected a bulk error with 1 error, got %v", err)
+	}
+
+	expected := map[string]string{"phone": "siren Disk full", "tablet": "none Disk full"}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Fatalf("expected %v, got %v", expected, sent)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	for i, device := range []Device{"broken", "phone", "tablet"} {
+		if results[i].Device != device || (results[i].Err != nil) != (device == "broken") {
+			t.Fatalf("unexpected result %+v for %q", results[i], device)
+		}
+	}
+}
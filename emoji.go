@@ -0,0 +1,58 @@
+package pushover
+
+import "strings"
+
+// emojiShortcodes maps the common shortcodes to their emoji.
+var emojiShortcodes = map[string]string{
+	":+1:":                         "\U0001F44D",
+	":-1:":                         "\U0001F44E",
+	":alarm_clock:":                "\u23F0",
+	":bell:":                       "\U0001F514",
+	":boom:":                       "\U0001F4A5",
+	":bug:":                        "\U0001F41B",
+	":calendar:":                   "\U0001F4C6",
+	":chart_with_downwards_trend:": "\U0001F4C9",
+	":chart_with_upwards_trend:":   "\U0001F4C8",
+	":check:":                      "\u2714\uFE0F",
+	":clock1:":                     "\U0001F550",
+	":construction:":               "\U0001F6A7",
+	":email:":                      "\U0001F4E7",
+	":exclamation:":                "\u2757",
+	":fire:":                       "\U0001F525",
+	":floppy_disk:":                "\U0001F4BE",
+	":green_circle:":               "\U0001F7E2",
+	":hourglass:":                  "\u231B",
+	":information_source:":         "\u2139\uFE0F",
+	":key:":                        "\U0001F511",
+	":lock:":                       "\U0001F512",
+	":mag:":                        "\U0001F50D",
+	":money_with_wings:":           "\U0001F4B8",
+	":no_entry:":                   "\u26D4",
+	":ok:":                         "\U0001F197",
+	":package:":                    "\U0001F4E6",
+	":question:":                   "\u2753",
+	":red_circle:":                 "\U0001F534",
+	":rocket:":                     "\U0001F680",
+	":rotating_light:":             "\U0001F6A8",
+	":skull:":                      "\U0001F480",
+	":sos:":                        "\U0001F198",
+	":tada:":                       "\U0001F389",
+	":thermometer:":                "\U0001F321\uFE0F",
+	":warning:":                    "\u26A0\uFE0F",
+	":white_check_mark:":           "\u2705",
+	":x:":                          "\u274C",
+	":yellow_circle:":              "\U0001F7E1",
+	":zap:":                        "\u26A1",
+}
+
+// emojiReplacer expands the shortcodes of emojiShortcodes.
+var emojiReplacer = newEmojiReplacer()
+
+func newEmojiReplacer() *strings.Replacer {
+	oldnew := make([]string, 0, 2*len(emojiShortcodes))
+	for shortcode, emoji := range emojiShortcodes {
+		oldnew = append(oldnew, shortcode, emoji)
+	}
+
+	return strings.NewReplacer(oldnew...)
+}
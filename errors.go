@@ -1,6 +1,7 @@
 package pushover
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -17,3 +18,62 @@ func (e Errors) Error() string {
 	}
 	return ret
 }
+
+// apiErrors maps the fragments of the API errors to the package errors, the
+// first match wins. The fragments are specific enough not to match the other
+// errors about the same field, e.g. an invalid callback URL.
+var apiErrors = []struct {
+	fragment string
+	err      error
+}{
+	{"application token", ErrInvalidToken},
+	{"user identifier", ErrInvalidRecipientToken},
+	{"user key", ErrInvalidRecipientToken},
+	{"receipt not found", ErrReceiptNotFound},
+	{"device name is not valid", ErrUnknownDevice},
+	{"message is empty", ErrMessageEmpty},
+	{"message cannot be blank", ErrMessageEmpty},
+	{"message cannot be longer", ErrMessageTooLong},
+	{"message is too long", ErrMessageTooLong},
+	{"url title is too long", ErrMessageURLTitleTooLong},
+	{"url title cannot be longer", ErrMessageURLTitleTooLong},
+	{"url_title cannot be longer", ErrMessageURLTitleTooLong},
+	{"url is too long", ErrMessageURLTooLong},
+	{"url cannot be longer", ErrMessageURLTooLong},
+	{"title is too long", ErrMessageTitleTooLong},
+	{"title cannot be longer", ErrMessageTitleTooLong},
+	{"priority is invalid", ErrInvalidPriority},
+	{"retry is invalid", ErrMissingEmergencyParameter},
+	{"expire is invalid", ErrMissingEmergencyParameter},
+	{"attachment is too large", ErrMessageAttachementTooLarge},
+	{"attachment cannot be larger", ErrMessageAttachementTooLarge},
+	{"sound is invalid", ErrUnknownSound},
+	{"sound is not valid", ErrUnknownSound},
+	{"message quota", ErrQuotaExceeded},
+}
+
+// AsErrors maps the API errors to the package errors, e.g. ErrMessageEmpty
+// for "message cannot be blank", to handle the same way the errors of the
+// local validation and of the API. The mapped errors wrap the package errors,
+// to be matched with errors.Is, and keep the text of the API errors like the
+// unknown errors.
+func (e Errors) AsErrors() []error {
+	ret := make([]error, 0, len(e))
+	for _, s := range e {
+		ret = append(ret, asError(s))
+	}
+
+	return ret
+}
+
+// asError maps an API error to a package error.
+func asError(s string) error {
+	lower := strings.ToLower(s)
+	for _, apiErr := range apiErrors {
+		if strings.Contains(lower, apiErr.fragment) {
+			return fmt.Errorf("%w: %s", apiErr.err, s)
+		}
+	}
+
+	return errors.New(s)
+}
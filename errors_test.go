@@ -1,7 +1,9 @@
 package pushover
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -15,3 +17,53 @@ func TestErrorsString(t *testing.T) {
 		t.Errorf("invalid error string\ngot:\n%s\nexpected:\n%s\n", got, expected)
 	}
 }
+
+// TestErrorsAsErrors tests the API errors mapped to the package errors
+func TestErrorsAsErrors(t *testing.T) {
+	tt := []struct {
+		apiErr   string
+		expected error
+	}{
+		{"message cannot be blank", ErrMessageEmpty},
+		{"user identifier is invalid", ErrInvalidRecipientToken},
+		{"application token is invalid", ErrInvalidToken},
+		{"device name is not valid for user", ErrUnknownDevice},
+		{"url title is too long", ErrMessageURLTitleTooLong},
+		{"url is too long", ErrMessageURLTooLong},
+		{"title cannot be longer than 250 characters", ErrMessageTitleTooLong},
+		{"retry is invalid", ErrMissingEmergencyParameter},
+		{"attachment is too large", ErrMessageAttachementTooLarge},
+		{"callback is not a valid URL", nil},
+		{"attachment type is not supported", nil},
+		{"something new", nil},
+	}
+
+	e := make(Errors, len(tt))
+	for i, tc := range tt {
+		e[i] = tc.apiErr
+	}
+
+	got := e.AsErrors()
+	if len(got) != len(tt) {
+		t.Fatalf("expected %d errors, got %d", len(tt), len(got))
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.apiErr, func(t *testing.T) {
+			if tc.expected == nil {
+				if !reflect.DeepEqual(got[i], errors.New(tc.apiErr)) {
+					t.Fatalf("expected the unknown error %q, got %v", tc.apiErr, got[i])
+				}
+				return
+			}
+
+			if !errors.Is(got[i], tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got[i])
+			}
+
+			if expected := tc.expected.Error() + ": " + tc.apiErr; got[i].Error() != expected {
+				t.Fatalf("expected %q, got %q", expected, got[i].Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package pushover
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Glance represents an update of the glance data displayed on the
+// recipient's widgets. The nil fields are left unchanged, a field set to its
+// zero value is sent, e.g. a Percent pointing to 0 shows an empty progress.
+type Glance struct {
+	Title   *string
+	Text    *string
+	Subtext *string
+	Count   *int
+	Percent *int
+	// DeviceName targets a single device of the recipient, all the devices
+	// are updated when it's empty.
+	DeviceName string
+}
+
+// Validate the glance values.
+func (g *Glance) validate() error {
+	if g.Title == nil && g.Text == nil && g.Subtext == nil && g.Count == nil && g.Percent == nil {
+		return ErrGlanceEmpty
+	}
+
+	for _, field := range []*string{g.Title, g.Text, g.Subtext} {
+		if field != nil && utf8.RuneCountInString(*field) > GlanceFieldMaxLength {
+			return ErrGlanceFieldTooLong
+		}
+	}
+
+	if g.Percent != nil && (*g.Percent < 0 || *g.Percent > 100) {
+		return ErrInvalidGlancePercent
+	}
+
+	if g.DeviceName != "" && !deviceNameRegexp.MatchString(g.DeviceName) {
+		return ErrInvalidDeviceName
+	}
+
+	return nil
+}
+
+// Return a map filled with the fields to update.
+func (g *Glance) toMap(pToken, rToken string) map[string]string {
+	ret := map[string]string{
+		"token": pToken,
+		"user":  rToken,
+	}
+
+	if g.Title != nil {
+		ret["title"] = *g.Title
+	}
+
+	if g.Text != nil {
+		ret["text"] = *g.Text
+	}
+
+	if g.Subtext != nil {
+		ret["subtext"] = *g.Subtext
+	}
+
+	if g.Count != nil {
+		ret["count"] = strconv.Itoa(*g.Count)
+	}
+
+	if g.Percent != nil {
+		ret["percent"] = strconv.Itoa(*g.Percent)
+	}
+
+	if g.DeviceName != "" {
+		ret["device"] = g.DeviceName
+	}
+
+	return ret
+}
+
+// SendGlanceUpdate updates the glance data of a recipient.
+func (p *Pushover) SendGlanceUpdate(glance *Glance, recipient *Recipient) (*Response, error) {
+	endpoint := fmt.Sprintf("%s/glances.json", APIEndpoint)
+
+	// Validate pushover
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate recipient
+	if err := recipient.validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate glance
+	if err := glance.validate(); err != nil {
+		return nil, err
+	}
+
+	req, err := newURLEncodedRequest("POST", endpoint, glance.toMap(p.token, recipient.token))
+	if err != nil {
+		return nil, p.redactError(err, recipient.token)
+	}
+
+	response := &Response{}
+	if err := p.do(req, response, false, recipient.token); err != nil {
+		return responseOnError(response, err)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,87 @@
+package pushover
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGlanceValidation tests the glance validation
+func TestGlanceValidation(t *testing.T) {
+	title := "Title"
+	longTitle := strings.Repeat("a", GlanceFieldMaxLength+1)
+	zero, negative, full, over := 0, -5, 100, 101
+
+	tt := []struct {
+		name   string
+		glance Glance
+		err    error
+	}{
+		{"empty glance", Glance{}, ErrGlanceEmpty},
+		{"valid glance", Glance{Title: &title, Count: &negative}, nil},
+		{"zero percent", Glance{Percent: &zero}, nil},
+		{"full percent", Glance{Percent: &full}, nil},
+		{"negative percent", Glance{Percent: &negative}, ErrInvalidGlancePercent},
+		{"percent over 100", Glance{Percent: &over}, ErrInvalidGlancePercent},
+		{"too long title", Glance{Title: &longTitle}, ErrGlanceFieldTooLong},
+		{"invalid device", Glance{Title: &title, DeviceName: "my^device"}, ErrInvalidDeviceName},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.glance.validate(); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestGlanceZeroValues tests that the zero values are sent and the nil
+// fields omitted
+func TestGlanceZeroValues(t *testing.T) {
+	zero, negative := 0, -3
+	empty := ""
+
+	glance := &Glance{Text: &empty, Count: &negative, Percent: &zero}
+	expected := map[string]string{
+		"token":   "pToken",
+		"user":    "rToken",
+		"text":    "",
+		"count":   "-3",
+		"percent": "0",
+	}
+
+	if got := glance.toMap("pToken", "rToken"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+// TestSendGlanceUpdate tests the glance update request
+func TestSendGlanceUpdate(t *testing.T) {
+	var percent string
+	var hasPercent bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/glances.json" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		r.ParseForm()
+		_, hasPercent = r.PostForm["percent"]
+		percent = r.PostForm.Get("percent")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	zero := 0
+	if _, err := fakePushover.SendGlanceUpdate(&Glance{Percent: &zero}, fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !hasPercent || percent != "0" {
+		t.Fatalf("expected a percent of 0, got %q", percent)
+	}
+}
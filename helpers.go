@@ -6,10 +6,24 @@ import (
 	"time"
 )
 
-// Helper to unmarshal a timestamp as string to a time.Time.
+// Helper to unmarshal a timestamp as string to a time.Time. The empty
+// timestamps, i.e. null, "" or 0, are kept nil.
 type timestamp struct{ *time.Time }
 
 func (t *timestamp) UnmarshalJSON(data []byte) error {
+	// Unquote the timestamps sent as string
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		if s == "" {
+			return nil
+		}
+		data = []byte(s)
+	}
+
 	var i int64
 	if err := json.Unmarshal(data, &i); err != nil {
 		return err
@@ -0,0 +1,33 @@
+package pushover
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// allowedTagsRegexp matches the HTML tags supported by Pushover.
+var allowedTagsRegexp = regexp.MustCompile(`(?i)</?(?:b|i|u)>|</font>|<font color="[#A-Za-z0-9]+">|</a>|<a href="[^"<>]*">`)
+
+// EscapeHTML escapes the text for an HTML message, the tags supported by
+// Pushover are kept: b, i, u, font with a color and a with an absolute http
+// or https href. The other tags and the special characters are escaped.
+func EscapeHTML(s string) string {
+	var ret strings.Builder
+	last := 0
+	for _, loc := range allowedTagsRegexp.FindAllStringIndex(s, -1) {
+		tag := s[loc[0]:loc[1]]
+
+		// The links to the other schemes, e.g. javascript:, are escaped
+		if validateHrefs(tag) != nil {
+			continue
+		}
+
+		ret.WriteString(html.EscapeString(s[last:loc[0]]))
+		ret.WriteString(tag)
+		last = loc[1]
+	}
+
+	ret.WriteString(html.EscapeString(s[last:]))
+	return ret.String()
+}
@@ -0,0 +1,29 @@
+package pushover
+
+import "testing"
+
+// TestEscapeHTML tests the escaped HTML messages
+func TestEscapeHTML(t *testing.T) {
+	tt := []struct {
+		name     string
+		s        string
+		expected string
+	}{
+		{"plain text", "Disk full", "Disk full"},
+		{"special characters", `a < b & "c"`, "a &lt; b &amp; &#34;c&#34;"},
+		{"supported tags", "<b>bold</b> <i>italic</i> <U>underline</U>", "<b>bold</b> <i>italic</i> <U>underline</U>"},
+		{"font and link", `<font color="#ff0000">red</font> <a href="https://example.com">site</a>`, `<font color="#ff0000">red</font> <a href="https://example.com">site</a>`},
+		{"unsupported tags", "<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, "&lt;a href=&#34;javascript:alert(1)&#34;&gt;x</a>"},
+		{"relative link", `<a href="/admin">x</a>`, "&lt;a href=&#34;/admin&#34;&gt;x</a>"},
+		{"attribute injection", `<font color="red" onclick="x">`, "&lt;font color=&#34;red&#34; onclick=&#34;x&#34;&gt;"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := EscapeHTML(tc.s); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
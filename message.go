@@ -2,21 +2,36 @@ package pushover
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var deviceNameRegexp *regexp.Regexp
+var hrefRegexp *regexp.Regexp
 
 func init() {
 	deviceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,25}$`)
+	hrefRegexp = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
 }
 
+// UseAppDefaultTitle is a title sent as no title at all, Pushover then uses
+// the app name. It opts out of the default title and the title prefix of the
+// app for a message.
+const UseAppDefaultTitle = "\x00app-default-title"
+
 // Message represents a pushover message.
 type Message struct {
 	// Required
@@ -30,57 +45,145 @@ type Message struct {
 	Timestamp   int64
 	Retry       time.Duration
 	Expire      time.Duration
+	TTL         time.Duration
 	CallbackURL string
-	DeviceName  string
-	Sound       string
-	HTML        bool
+	// DeviceName targets a single device of the recipient, all the devices
+	// are notified when it's empty along with Devices.
+	DeviceName string
+	// Devices targets more devices of the recipient, along with DeviceName.
+	Devices []Device
+	Sound   string
+	HTML    bool
 
 	// attachment
-	attachment io.Reader
+	attachment         io.Reader
+	attachmentName     string
+	attachmentType     string
+	attachmentChecksum string
+	attachmentSize     int64
+	attachmentProgress func(sent, total int64)
 }
 
 // NewMessage returns a simple new message.
 func NewMessage(message string) *Message {
-	return &Message{Message: message}
+	return &Message{Message: message, Priority: UnsetPriority}
 }
 
 // NewMessageWithTitle returns a simple new message with a title.
 func NewMessageWithTitle(message, title string) *Message {
-	return &Message{Message: message, Title: title}
+	return &Message{Message: message, Title: title, Priority: UnsetPriority}
+}
+
+// NewLowestPriorityMessage returns a new message with the lowest priority,
+// no notification is generated for this message.
+func NewLowestPriorityMessage(message string) *Message {
+	return &Message{Message: message, Priority: PriorityLowest}
+}
+
+// NewLowPriorityMessage returns a new message with a low priority, the
+// notification is sent without sound nor vibration.
+func NewLowPriorityMessage(message string) *Message {
+	return &Message{Message: message, Priority: PriorityLow}
+}
+
+// NewHighPriorityMessage returns a new message with a high priority, the
+// notification bypasses the user's quiet hours.
+func NewHighPriorityMessage(message string) *Message {
+	return &Message{Message: message, Priority: PriorityHigh}
+}
+
+// NewEmergencyMessage returns a new message with an emergency priority, the
+// notification is repeated every retry duration until it's acknowledged or
+// expired. The retry and expire parameters are checked when the message is
+// sent, an ErrMissingEmergencyParameter is returned if one of them is zero.
+func NewEmergencyMessage(message string, retry, expire time.Duration) *Message {
+	return &Message{
+		Message:  message,
+		Priority: PriorityEmergency,
+		Retry:    retry,
+		Expire:   expire,
+	}
 }
 
 // AddAttachment adds an attachment to the message it's programmer's
 // responsibility to close the reader.
 func (m *Message) AddAttachment(attachment io.Reader) error {
 	m.attachment = attachment
+	m.attachmentName = ""
+	m.attachmentType = ""
+	m.attachmentChecksum = ""
+	m.attachmentSize = 0
 	return nil
 }
 
+// IsSilent returns true if the message doesn't alert the recipient with a
+// sound or a vibration, the lowest and low priorities are delivered quietly.
+func (m *Message) IsSilent() bool {
+	return m.Priority <= PriorityLow || m.Sound == SoundNone
+}
+
+// Fits returns true if all the message fields are within their length
+// limits.
+func (m *Message) Fits() bool {
+	field, _ := m.Overflow()
+	return field == ""
+}
+
+// Overflow returns the first field exceeding its length limit, named after
+// its API parameter, and the number of characters over the limit. It returns
+// an empty field if the message fits.
+func (m *Message) Overflow() (field string, excess int) {
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"message", m.Message, MessageMaxLength},
+		{"title", m.Title, MessageTitleMaxLength},
+		{"url", m.URL, MessageURLMaxLength},
+		{"url_title", m.URLTitle, MessageURLTitleMaxLength},
+	}
+
+	for _, f := range fields {
+		if length := utf8.RuneCountInString(f.value); length > f.max {
+			return f.name, length - f.max
+		}
+	}
+
+	return "", 0
+}
+
 // Validate the message values.
 func (m *Message) validate() error {
-	// Message should no be empty
-	if m.Message == "" {
+	return m.validateWithLimits(MessageMaxLength, MessageTitleMaxLength)
+}
+
+// validateWithLimits validates the message values with the given message and
+// title max number of characters.
+func (m *Message) validateWithLimits(maxLength, maxTitleLength int) error {
+	// Message should no be empty, nor blank
+	if strings.TrimSpace(m.Message) == "" {
 		return ErrMessageEmpty
 	}
 
 	// Validate message length
-	if len(m.Message) > MessageMaxLength {
+	if utf8.RuneCountInString(m.Message) > maxLength {
 		return ErrMessageTooLong
 	}
 
 	// Validate Title field length
-	if len(m.Title) > MessageTitleMaxLength {
+	if utf8.RuneCountInString(m.Title) > maxTitleLength {
 		return ErrMessageTitleTooLong
 	}
 
-	// Validate URL field
-	if len(m.URL) > MessageURLMaxLength {
-		return ErrMessageURLTooLong
+	// Validate control characters
+	if hasControlChars(m.Message) || hasControlChars(m.Title) {
+		return ErrInvalidControlChars
 	}
 
-	// Validate URL title field
-	if len(m.URLTitle) > MessageURLTitleMaxLength {
-		return ErrMessageURLTitleTooLong
+	// Validate URL field
+	if utf8.RuneCountInString(m.URL) > MessageURLMaxLength {
+		return ErrMessageURLTooLong
 	}
 
 	// URLTitle should not be set with an empty URL
@@ -88,8 +191,20 @@ func (m *Message) validate() error {
 		return ErrEmptyURL
 	}
 
+	// Validate URL title field, only relevant along with an URL
+	if utf8.RuneCountInString(m.URLTitle) > MessageURLTitleMaxLength {
+		return ErrMessageURLTitleTooLong
+	}
+
+	// Validate the links of the HTML messages
+	if m.HTML {
+		if err := validateHrefs(m.Message); err != nil {
+			return err
+		}
+	}
+
 	// Validate priorities
-	if m.Priority > PriorityEmergency || m.Priority < PriorityLowest {
+	if m.Priority != UnsetPriority && (m.Priority > PriorityEmergency || m.Priority < PriorityLowest) {
 		return ErrInvalidPriority
 	}
 
@@ -100,9 +215,20 @@ func (m *Message) validate() error {
 		}
 	}
 
+	// Validate TTL, the emergency notifications use the expire parameter
+	if m.TTL != 0 {
+		if m.TTL < time.Second {
+			return ErrInvalidTTL
+		}
+
+		if m.Priority == PriorityEmergency {
+			return ErrTTLWithEmergency
+		}
+	}
+
 	// Test device name
-	if m.DeviceName != "" {
-		if deviceNameRegexp.MatchString(m.DeviceName) == false {
+	for _, device := range m.deviceNames() {
+		if deviceNameRegexp.MatchString(device) == false {
 			return ErrInvalidDeviceName
 		}
 	}
@@ -110,6 +236,52 @@ func (m *Message) validate() error {
 	return nil
 }
 
+// validateHrefs returns an ErrInvalidURL wrapped with the first link of the
+// HTML message which isn't an absolute http or https URL.
+func validateHrefs(html string) error {
+	for _, match := range hrefRegexp.FindAllStringSubmatch(html, -1) {
+		href := match[1] + match[2] + match[3]
+		u, err := url.Parse(href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidURL, href)
+		}
+	}
+
+	return nil
+}
+
+// deviceNames returns the names of the devices targeted by the message, none
+// if all the devices are targeted.
+func (m *Message) deviceNames() []string {
+	var names []string
+	if m.DeviceName != "" {
+		names = append(names, m.DeviceName)
+	}
+
+	for _, device := range m.Devices {
+		names = append(names, string(device))
+	}
+
+	return names
+}
+
+// hasControlChars returns true if the string contains control characters
+// other than new lines and tabulations.
+func hasControlChars(s string) bool {
+	for _, r := range s {
+		switch r {
+		case '\n', '\r', '\t':
+			continue
+		}
+
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Return a map filled with the relevant data.
 func (m *Message) toMap(pToken, rToken string) map[string]string {
 	ret := map[string]string{
@@ -119,6 +291,10 @@ func (m *Message) toMap(pToken, rToken string) map[string]string {
 		"priority": strconv.Itoa(m.Priority),
 	}
 
+	if m.Priority == UnsetPriority {
+		ret["priority"] = strconv.Itoa(PriorityNormal)
+	}
+
 	if m.Title != "" {
 		ret["title"] = m.Title
 	}
@@ -135,8 +311,8 @@ func (m *Message) toMap(pToken, rToken string) map[string]string {
 		ret["sound"] = m.Sound
 	}
 
-	if m.DeviceName != "" {
-		ret["device"] = m.DeviceName
+	if devices := m.deviceNames(); len(devices) > 0 {
+		ret["device"] = strings.Join(devices, ",")
 	}
 
 	if m.Timestamp != 0 {
@@ -147,6 +323,10 @@ func (m *Message) toMap(pToken, rToken string) map[string]string {
 		ret["html"] = "1"
 	}
 
+	if m.TTL > 0 {
+		ret["ttl"] = strconv.FormatInt(int64(m.TTL/time.Second), 10)
+	}
+
 	if m.Priority == PriorityEmergency {
 		ret["retry"] = strconv.FormatFloat(m.Retry.Seconds(), 'f', -1, 64)
 		ret["expire"] = strconv.FormatFloat(m.Expire.Seconds(), 'f', -1, 64)
@@ -158,35 +338,46 @@ func (m *Message) toMap(pToken, rToken string) map[string]string {
 	return ret
 }
 
-// Send sends the message using the pushover and the recipient tokens.
-func (m *Message) send(pToken, rToken string) (*Response, error) {
+// Send sends the message using the pushover app and the recipient token.
+func (m *Message) send(ctx context.Context, p *Pushover, rToken string) (*Response, error) {
+	pToken := p.token
 	url := fmt.Sprintf("%s/messages.json", APIEndpoint)
 
 	var f func(string, string, string) (*http.Request, error)
-	if m.attachment == nil {
-		// Use a multipart request if a file should be sent
+	switch {
+	case p.jsonTransport:
+		// Use a JSON request if the app talks to a JSON only gateway
+		f = m.jsonRequest
+	case m.attachment == nil && m.attachmentName == "" && m.attachmentType == "":
+		// Use a url encoded request if there is no file to send, a half
+		// configured attachment fails in the multipart request
 		f = m.urlEncodedRequest
-	} else {
-		// Use a url encoded request otherwise
-		f = m.multipartRequest
+	default:
+		// Use a multipart request otherwise
+		f = func(pToken, rToken, url string) (*http.Request, error) {
+			return m.multipartRequest(pToken, rToken, url, p.multipartBoundary)
+		}
 	}
 
 	// Post the from and check the headers of the response
 	req, err := f(pToken, rToken, url)
 	if err != nil {
-		return nil, err
+		return nil, p.redactError(err, rToken)
 	}
+	req = req.WithContext(ctx)
 
-	resp := &Response{}
-	if err := do(req, resp, true); err != nil {
-		return nil, err
+	resp := &Response{CorrelationID: CorrelationIDFromContext(ctx)}
+	if err := p.do(req, resp, true, rToken); err != nil {
+		return responseOnError(resp, err)
 	}
 
 	return resp, nil
 }
 
 // multipartRequest returns a new multipart POST request with a file attached.
-func (m *Message) multipartRequest(pToken, rToken, url string) (*http.Request, error) {
+// A random boundary is used if the given boundary is empty. The attachment is
+// written first, followed by the params sorted by name.
+func (m *Message) multipartRequest(pToken, rToken, url, boundary string) (*http.Request, error) {
 	body := &bytes.Buffer{}
 
 	if m.attachment == nil {
@@ -195,12 +386,18 @@ func (m *Message) multipartRequest(pToken, rToken, url string) (*http.Request, e
 
 	// Write the body as multipart form data
 	w := multipart.NewWriter(body)
+	if boundary != "" {
+		if err := w.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
+	}
 
 	// Write the file in the body
-	fw, err := w.CreateFormFile("attachment", "attachment")
+	fw, err := m.createAttachmentPart(w)
 	if err != nil {
 		return nil, err
 	}
+	attachmentStart := int64(body.Len())
 
 	written, err := io.Copy(fw, m.attachment)
 	if err != nil {
@@ -211,9 +408,20 @@ func (m *Message) multipartRequest(pToken, rToken, url string) (*http.Request, e
 		return nil, ErrMessageAttachementTooLarge
 	}
 
-	// Handle params
-	for k, v := range m.toMap(pToken, rToken) {
-		if err := w.WriteField(k, v); err != nil {
+	if m.attachmentSize > 0 && written != m.attachmentSize {
+		return nil, ErrAttachmentSizeMismatch
+	}
+
+	// Handle params, sorted by name to get reproducible requests
+	params := m.toMap(pToken, rToken)
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := w.WriteField(k, params[k]); err != nil {
 			return nil, err
 		}
 	}
@@ -228,6 +436,75 @@ func (m *Message) multipartRequest(pToken, rToken, url string) (*http.Request, e
 	}
 	req.Header.Set("Content-Type", w.FormDataContentType())
 
+	// Report the progress as the attachment is uploaded
+	if m.attachmentProgress != nil {
+		data := body.Bytes()
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(&progressReader{
+				r:        bytes.NewReader(data),
+				start:    attachmentStart,
+				total:    written,
+				progress: m.attachmentProgress,
+			}), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+
+	return req, nil
+}
+
+// readAttachment reads the whole attachment, it returns an
+// ErrMessageAttachementTooLarge if the attachment is too large.
+func (m *Message) readAttachment() ([]byte, error) {
+	attachment, err := io.ReadAll(io.LimitReader(m.attachment, MessageMaxAttachementByte+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(attachment) > MessageMaxAttachementByte {
+		return nil, ErrMessageAttachementTooLarge
+	}
+
+	if m.attachmentSize > 0 && int64(len(attachment)) != m.attachmentSize {
+		return nil, ErrAttachmentSizeMismatch
+	}
+
+	return attachment, nil
+}
+
+// jsonRequest returns a new JSON POST request, the attachment is encoded in
+// base64.
+func (m *Message) jsonRequest(pToken, rToken, url string) (*http.Request, error) {
+	params := m.toMap(pToken, rToken)
+
+	if m.attachment == nil && (m.attachmentName != "" || m.attachmentType != "") {
+		return nil, ErrMissingAttachement
+	}
+
+	if m.attachment != nil {
+		attachment, err := m.readAttachment()
+		if err != nil {
+			return nil, err
+		}
+
+		params["attachment_base64"] = base64.StdEncoding.EncodeToString(attachment)
+		params["attachment_type"] = m.attachmentType
+		if m.attachmentType == "" {
+			params["attachment_type"] = http.DetectContentType(attachment)
+		}
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
 	return req, nil
 }
 
@@ -3,9 +3,14 @@ package pushover
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -74,6 +79,11 @@ func TestMessageValidation(t *testing.T) {
 			message:     Message{},
 			expectedErr: ErrMessageEmpty,
 		},
+		{
+			name:        "whitespace only message",
+			message:     Message{Message: " \n\t\n"},
+			expectedErr: ErrMessageEmpty,
+		},
 		{
 			name: "message with valid size",
 			message: Message{
@@ -138,6 +148,23 @@ func TestMessageValidation(t *testing.T) {
 			},
 			expectedErr: ErrMessageURLTitleTooLong,
 		},
+		{
+			name: "message with multibyte characters within the limits",
+			message: Message{
+				Message:  strings.Repeat("é", MessageMaxLength),
+				Title:    strings.Repeat("🔥", MessageTitleMaxLength),
+				URL:      "http://google.com/" + strings.Repeat("é", MessageURLMaxLength-len("http://google.com/")),
+				URLTitle: strings.Repeat("é", MessageURLTitleMaxLength),
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "message with too many multibyte characters",
+			message: Message{
+				Message: strings.Repeat("é", MessageMaxLength+1),
+			},
+			expectedErr: ErrMessageTooLong,
+		},
 		{
 			name: "message with URL without URL title",
 			message: Message{
@@ -172,6 +199,64 @@ func TestMessageValidation(t *testing.T) {
 			},
 			expectedErr: ErrInvalidPriority,
 		},
+		{
+			name: "message with new lines and tabulations",
+			message: Message{
+				Message: "Test\tmessage\r\non many lines\n",
+				Title:   "Test\ttitle",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "message with control characters",
+			message: Message{
+				Message: "Test \x1b[31mmessage",
+			},
+			expectedErr: ErrInvalidControlChars,
+		},
+		{
+			name: "message with control characters in the title",
+			message: Message{
+				Message: "Test message",
+				Title:   "Test\x00title",
+			},
+			expectedErr: ErrInvalidControlChars,
+		},
+		{
+			name: "message with TTL",
+			message: Message{
+				Message: "Test message",
+				TTL:     time.Hour,
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "message with negative TTL",
+			message: Message{
+				Message: "Test message",
+				TTL:     -time.Hour,
+			},
+			expectedErr: ErrInvalidTTL,
+		},
+		{
+			name: "message with TTL under a second",
+			message: Message{
+				Message: "Test message",
+				TTL:     500 * time.Millisecond,
+			},
+			expectedErr: ErrInvalidTTL,
+		},
+		{
+			name: "message with TTL and emergency priority",
+			message: Message{
+				Message:  "Test message",
+				Priority: PriorityEmergency,
+				Expire:   time.Hour,
+				Retry:    60 * time.Second,
+				TTL:      time.Hour,
+			},
+			expectedErr: ErrTTLWithEmergency,
+		},
 	}
 
 	for _, tc := range tt {
@@ -211,13 +296,47 @@ func TestMessageDeviceName(t *testing.T) {
 	}
 }
 
+// TestMessageTTLParam tests the TTL param encoding
+func TestMessageTTLParam(t *testing.T) {
+	tt := []struct {
+		name     string
+		ttl      time.Duration
+		expected string
+		present  bool
+	}{
+		{"no TTL", 0, "", false},
+		{"TTL in seconds", 90 * time.Second, "90", true},
+		{"TTL in hours", 2 * time.Hour, "7200", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := &Message{
+				Message:  "Test message",
+				Priority: PriorityNormal,
+				TTL:      tc.ttl,
+			}
+
+			got, ok := message.toMap("pToken", "rToken")["ttl"]
+			if ok != tc.present {
+				t.Fatalf("expected ttl presence to be %t, got %t", tc.present, ok)
+			}
+
+			if got != tc.expected {
+				t.Fatalf("expected ttl %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
 // TestNewMessageWithTitle
 func TestNewMessageWithTitle(t *testing.T) {
 	message := NewMessageWithTitle("World", "Hello")
 
 	expected := &Message{
-		Title:   "Hello",
-		Message: "World",
+		Title:    "Hello",
+		Message:  "World",
+		Priority: UnsetPriority,
 	}
 
 	if !reflect.DeepEqual(message, expected) {
@@ -225,6 +344,53 @@ func TestNewMessageWithTitle(t *testing.T) {
 	}
 }
 
+// TestNewPriorityMessages tests the priority messages constructors
+func TestNewPriorityMessages(t *testing.T) {
+	tt := []struct {
+		name     string
+		message  *Message
+		expected *Message
+	}{
+		{
+			name:     "lowest priority",
+			message:  NewLowestPriorityMessage("Hello"),
+			expected: &Message{Message: "Hello", Priority: PriorityLowest},
+		},
+		{
+			name:     "low priority",
+			message:  NewLowPriorityMessage("Hello"),
+			expected: &Message{Message: "Hello", Priority: PriorityLow},
+		},
+		{
+			name:     "high priority",
+			message:  NewHighPriorityMessage("Hello"),
+			expected: &Message{Message: "Hello", Priority: PriorityHigh},
+		},
+		{
+			name:    "emergency priority",
+			message: NewEmergencyMessage("Hello", time.Minute, time.Hour),
+			expected: &Message{
+				Message:  "Hello",
+				Priority: PriorityEmergency,
+				Retry:    time.Minute,
+				Expire:   time.Hour,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.message, tc.expected) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, tc.message)
+			}
+
+			if err := tc.message.validate(); err != nil {
+				t.Fatalf("expected a valid message, got %v", err)
+			}
+		})
+	}
+}
+
 // TestMutlipartRequest
 func TestMutlipartRequest(t *testing.T) {
 	tt := []struct {
@@ -258,7 +424,7 @@ func TestMutlipartRequest(t *testing.T) {
 				message.AddAttachment(attachement)
 			}
 
-			req, err := message.multipartRequest("pToken", "rToken", "url")
+			req, err := message.multipartRequest("pToken", "rToken", "url", "")
 			if err != tc.expectedErr {
 				t.Fatalf("expected %q, got %q", tc.expectedErr, err)
 			}
@@ -303,3 +469,279 @@ func TestMutlipartRequest(t *testing.T) {
 		})
 	}
 }
+
+// TestJSONRequest tests the JSON request with an attachment
+func TestJSONRequest(t *testing.T) {
+	message := NewMessageWithTitle("World", "Hello")
+	attachment := []byte("GIF89a fake image")
+	message.AddAttachment(bytes.NewReader(attachment))
+
+	req, err := message.jsonRequest("pToken", "rToken", "url")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if contentType := req.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("invalid content type: %s", contentType)
+	}
+
+	var got map[string]string
+	if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"token":             "pToken",
+		"user":              "rToken",
+		"message":           "World",
+		"priority":          "0",
+		"title":             "Hello",
+		"attachment_base64": base64.StdEncoding.EncodeToString(attachment),
+		"attachment_type":   "image/gif",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("invalid JSON body, expected %v, got %v", expected, got)
+	}
+}
+
+// TestMessageEmptyDeviceName tests that the device param is omitted to
+// notify all the devices
+func TestMessageEmptyDeviceName(t *testing.T) {
+	tt := []struct {
+		name     string
+		device   string
+		expected []string
+	}{
+		{"all devices", "", nil},
+		{"single device", "droid-2", []string{"droid-2"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage("Hello")
+			message.DeviceName = tc.device
+			message.AddAttachment(bytes.NewBufferString("attachment"))
+
+			req, err := message.multipartRequest("pToken", "rToken", "url", "")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if err := req.ParseMultipartForm(1024); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			got, ok := req.MultipartForm.Value["device"]
+			if ok != (tc.expected != nil) || !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected device %v, got %v", tc.expected, got)
+			}
+
+			if _, ok := message.toMap("pToken", "rToken")["device"]; ok != (tc.device != "") {
+				t.Fatalf("unexpected device param in the url encoded request")
+			}
+		})
+	}
+}
+
+// TestMessageURLAndURLTitle tests the URL and URL title combinations
+func TestMessageURLAndURLTitle(t *testing.T) {
+	longURL, err := getRandomString(MessageURLMaxLength + 1)
+	if err != nil {
+		t.Fatalf("failed to create a random string: %v", err)
+	}
+
+	longURLTitle, err := getRandomString(MessageURLTitleMaxLength + 1)
+	if err != nil {
+		t.Fatalf("failed to create a random string: %v", err)
+	}
+
+	tt := []struct {
+		name     string
+		url      string
+		urlTitle string
+		err      error
+	}{
+		{"no URL nor URL title", "", "", nil},
+		{"URL without URL title", "http://google.com", "", nil},
+		{"URL with URL title", "http://google.com", "Google", nil},
+		{"URL title without URL", "", "Google", ErrEmptyURL},
+		{"too long URL title without URL", "", longURLTitle, ErrEmptyURL},
+		{"too long URL title with URL", "http://google.com", longURLTitle, ErrMessageURLTitleTooLong},
+		{"too long URL without URL title", longURL, "", ErrMessageURLTooLong},
+		{"too long URL with URL title", longURL, "Google", ErrMessageURLTooLong},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := Message{
+				Message:  "Test message",
+				URL:      tc.url,
+				URLTitle: tc.urlTitle,
+			}
+			if err := message.validate(); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestMessageOverflow tests the fields overflow
+func TestMessageOverflow(t *testing.T) {
+	tt := []struct {
+		name           string
+		message        *Message
+		expectedField  string
+		expectedExcess int
+	}{
+		{
+			name:    "message fits",
+			message: NewMessageWithTitle("Hello", "World"),
+		},
+		{
+			name:           "message too long",
+			message:        NewMessage(strings.Repeat("a", MessageMaxLength+10)),
+			expectedField:  "message",
+			expectedExcess: 10,
+		},
+		{
+			name:           "title too long",
+			message:        NewMessageWithTitle("Hello", strings.Repeat("é", MessageTitleMaxLength+3)),
+			expectedField:  "title",
+			expectedExcess: 3,
+		},
+		{
+			name:    "multibyte title fits",
+			message: NewMessageWithTitle("Hello", strings.Repeat("é", MessageTitleMaxLength)),
+		},
+		{
+			name: "URL title too long",
+			message: &Message{
+				Message:  "Hello",
+				URL:      "http://google.com",
+				URLTitle: strings.Repeat("a", MessageURLTitleMaxLength+1),
+			},
+			expectedField:  "url_title",
+			expectedExcess: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			field, excess := tc.message.Overflow()
+			if field != tc.expectedField || excess != tc.expectedExcess {
+				t.Fatalf("expected %q over by %d, got %q over by %d",
+					tc.expectedField, tc.expectedExcess, field, excess)
+			}
+
+			if fits := tc.message.Fits(); fits != (tc.expectedField == "") {
+				t.Fatalf("unexpected fits value: %t", fits)
+			}
+		})
+	}
+}
+
+// TestMessageIsSilent tests the messages delivered without alert
+func TestMessageIsSilent(t *testing.T) {
+	tt := []struct {
+		name     string
+		message  *Message
+		sound    string
+		expected bool
+	}{
+		{"normal priority", NewMessage("Hello"), "", false},
+		{"lowest priority", NewLowestPriorityMessage("Hello"), "", true},
+		{"low priority", NewLowPriorityMessage("Hello"), "", true},
+		{"high priority", NewHighPriorityMessage("Hello"), "", false},
+		{"no sound", NewMessage("Hello"), SoundNone, true},
+		{"custom sound", NewMessage("Hello"), SoundSiren, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.message.Sound = tc.sound
+			if got := tc.message.IsSilent(); got != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestMessageHTMLLinks tests the validation of the links of HTML messages
+func TestMessageHTMLLinks(t *testing.T) {
+	tt := []struct {
+		name    string
+		message string
+		html    bool
+		href    string
+	}{
+		{"no link", "<b>Hello</b>", true, ""},
+		{"http link", `<a href="http://example.com">site</a>`, true, ""},
+		{"https link", `<a class="x" href='https://example.com/a?b=c'>site</a>`, true, ""},
+		{"javascript link", `<a href="javascript:alert(1)">site</a>`, true, "javascript:alert(1)"},
+		{"relative link", `<a href=/path>site</a>`, true, "/path"},
+		{"second link", `<a href="https://example.com">ok</a> <A HREF="ftp://example.com">ko</A>`, true, "ftp://example.com"},
+		{"not html", `<a href="javascript:alert(1)">site</a>`, false, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			message := NewMessage(tc.message)
+			message.HTML = tc.html
+			err := message.validate()
+			if tc.href == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidURL) {
+				t.Fatalf("expected %v, got %v", ErrInvalidURL, err)
+			}
+
+			if !strings.Contains(err.Error(), tc.href) {
+				t.Fatalf("expected the error %q to contain %q", err, tc.href)
+			}
+		})
+	}
+}
+
+// TestMultipartFieldsOrder tests that the multipart requests are reproducible
+func TestMultipartFieldsOrder(t *testing.T) {
+	message := NewMessageWithTitle("Hello", "Title")
+	message.URL = "https://example.com"
+	message.Sound = SoundSiren
+
+	var expected []byte
+	for i := 0; i < 10; i++ {
+		message.AddAttachment(strings.NewReader("attachment"))
+		req, err := message.multipartRequest("pToken", "rToken", "url", "boundary")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if expected == nil {
+			expected = body
+			continue
+		}
+
+		if !bytes.Equal(body, expected) {
+			t.Fatalf("expected the same body on each request")
+		}
+	}
+
+	// The fields are sorted after the attachment
+	body := string(expected)
+	fields := []string{`name="attachment"`, `name="message"`, `name="sound"`, `name="title"`, `name="token"`, `name="url"`, `name="user"`}
+	for i := 1; i < len(fields); i++ {
+		if strings.Index(body, fields[i-1]) > strings.Index(body, fields[i]) {
+			t.Fatalf("expected %s before %s", fields[i-1], fields[i])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pushover
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// MetricsCollector receives an observation of each message sent to the API,
+// e.g. to feed Prometheus histograms. It must be safe for concurrent use.
+type MetricsCollector interface {
+	ObserveSend(SendObservation)
+}
+
+// SendObservation describes a message sent to the API.
+type SendObservation struct {
+	// MessageLength is the number of characters of the message body.
+	MessageLength int
+	// Duration is the duration of the send, retries included.
+	Duration time.Duration
+	// Err is the error of the send, nil if it succeeded.
+	Err error
+	// CorrelationID is the correlation id of the send context.
+	CorrelationID string
+	// Labels are the metric labels of the send context.
+	Labels map[string]string
+}
+
+// metricLabelsKey is the context key of the metric labels.
+type metricLabelsKey struct{}
+
+// ContextWithMetricLabels returns a context carrying labels passed to the
+// MetricsCollector with the observations of the messages sent with it, e.g.
+// the tenant. The labels are never sent to the API.
+func ContextWithMetricLabels(ctx context.Context, labels map[string]string) context.Context {
+	return context.WithValue(ctx, metricLabelsKey{}, labels)
+}
+
+// MetricLabelsFromContext returns the metric labels of the context, or nil if
+// there are none.
+func MetricLabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(metricLabelsKey{}).(map[string]string)
+	return labels
+}
+
+// observeSend reports the send to the slow send hook and to the metrics
+// collector, if any.
+func (p *Pushover) observeSend(ctx context.Context, message *Message, start time.Time, err error) {
+	duration := p.now().Sub(start)
+	if p.onSlowSend != nil && duration > p.slowSendThreshold {
+		p.onSlowSend(duration, message)
+	}
+
+	if p.metrics == nil {
+		return
+	}
+
+	p.metrics.ObserveSend(SendObservation{
+		MessageLength: utf8.RuneCountInString(message.Message),
+		Duration:      duration,
+		Err:           err,
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Labels:        MetricLabelsFromContext(ctx),
+	})
+}
+
+// Stats are the cumulative counts of the messages sent by an app.
+type Stats struct {
+	// Sends is the number of messages sent to the API.
+	Sends int64
+	// Successes and Failures are the numbers of messages accepted and not
+	// accepted by the API.
+	Successes int64
+	Failures  int64
+	// Retries is the number of requests retried.
+	Retries int64
+}
+
+// counters are the app stats, safe for concurrent use.
+type counters struct {
+	sends     atomic.Int64
+	successes atomic.Int64
+	failures  atomic.Int64
+	retries   atomic.Int64
+}
+
+// observe counts a message sent with its error.
+func (c *counters) observe(err error) {
+	c.sends.Add(1)
+	if err != nil {
+		c.failures.Add(1)
+	} else {
+		c.successes.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the app stats, e.g. for a debug page without
+// a MetricsCollector.
+func (p *Pushover) Stats() Stats {
+	return Stats{
+		Sends:     p.stats.sends.Load(),
+		Successes: p.stats.successes.Load(),
+		Failures:  p.stats.failures.Load(),
+		Retries:   p.stats.retries.Load(),
+	}
+}
@@ -0,0 +1,156 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCollector keeps the observations
+type fakeCollector struct {
+	mu           sync.Mutex
+	observations []SendObservation
+}
+
+func (c *fakeCollector) ObserveSend(o SendObservation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observations = append(c.observations, o)
+}
+
+// TestMetricsCollector tests the observations of the sends
+func TestMetricsCollector(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	collector := &fakeCollector{}
+	p := New(fakePushover.token, WithMetricsCollector(collector))
+
+	ctx := ContextWithCorrelationID(context.Background(), "trace-id")
+	p.SendMessageContext(ctx, NewMessage("Hello é"), fakeRecipient)
+	p.SendMessage(NewMessage("Hello"), fakeInvalidRecipient)
+
+	// The messages failing the local validation are not sent
+	p.SendMessage(NewMessage(""), fakeRecipient)
+
+	if len(collector.observations) != 2 {
+		t.Fatalf("expected 2 observations, got %d", len(collector.observations))
+	}
+
+	first, second := collector.observations[0], collector.observations[1]
+	if first.MessageLength != 7 || first.Err != nil || first.CorrelationID != "trace-id" {
+		t.Fatalf("unexpected observation %+v", first)
+	}
+
+	if second.MessageLength != 5 || second.Err == nil {
+		t.Fatalf("unexpected observation %+v", second)
+	}
+}
+
+// TestMetricLabels tests the labels passed to the metrics collector only
+func TestMetricLabels(t *testing.T) {
+	labels := map[string]string{"tenant": "acme-tenant"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(b), "acme-tenant") || strings.Contains(r.URL.String(), "acme-tenant") {
+			t.Errorf("the labels should not be sent")
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	collector := &fakeCollector{}
+	p := New(fakePushover.token, WithMetricsCollector(collector))
+	p.SendMessageContext(ContextWithMetricLabels(context.Background(), labels), NewMessage("Hello"), fakeRecipient)
+	p.SendMessage(NewMessage("Hello"), fakeRecipient)
+
+	if len(collector.observations) != 2 {
+		t.Fatalf("expected 2 observations, got %d", len(collector.observations))
+	}
+
+	if !reflect.DeepEqual(collector.observations[0].Labels, labels) {
+		t.Fatalf("expected labels %v, got %v", labels, collector.observations[0].Labels)
+	}
+
+	if collector.observations[1].Labels != nil {
+		t.Fatalf("expected no labels, got %v", collector.observations[1].Labels)
+	}
+}
+
+// TestStats tests the counts of the messages sent
+func TestStats(t *testing.T) {
+	failures := 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if r.FormValue("user") == fakeInvalidRecipient.token {
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	p := New(fakePushover.token, WithRetries(1, time.Millisecond))
+	p.SendMessage(NewMessage("Hello"), fakeRecipient)
+	p.SendMessage(NewMessage("Hello"), fakeRecipient)
+	p.SendMessage(NewMessage("Hello"), fakeInvalidRecipient)
+
+	expected := Stats{Sends: 3, Successes: 2, Failures: 1, Retries: 1}
+	if got := p.Stats(); got != expected {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+// TestSlowSendThreshold tests the hook called for the slow sends only
+func TestSlowSendThreshold(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	// Each call of the clock moves it forward
+	now := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)
+	step := time.Second
+	clock := func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+
+	var latencies []time.Duration
+	var messages []string
+	p := New(fakePushover.token, WithClock(clock), WithSlowSendThreshold(2*time.Second, func(latency time.Duration, m *Message) {
+		latencies = append(latencies, latency)
+		messages = append(messages, m.Message)
+	}))
+
+	p.SendMessage(NewMessage("Fast"), fakeRecipient)
+
+	step = 3 * time.Second
+	p.SendMessage(NewMessage("Slow"), fakeRecipient)
+
+	if !reflect.DeepEqual(latencies, []time.Duration{3 * time.Second}) || !reflect.DeepEqual(messages, []string{"Slow"}) {
+		t.Fatalf("unexpected slow sends %v %v", latencies, messages)
+	}
+}
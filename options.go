@@ -0,0 +1,437 @@
+package pushover
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// Option is used to configure the Pushover app.
+type Option func(*Pushover)
+
+// httpTransport returns the transport of the app, a copy of the default one
+// is created on the first call. Like any custom transport of net/http, it
+// only attempts HTTP/2 with WithForceHTTP2.
+func (p *Pushover) httpTransport() *http.Transport {
+	if p.transport == nil {
+		p.transport = http.DefaultTransport.(*http.Transport).Clone()
+		p.transport.ForceAttemptHTTP2 = false
+	}
+
+	return p.transport
+}
+
+// WithStrictResponseDecoding makes the requests fail when the API responds
+// with fields unknown to this package. This is meant to be used in test or
+// staging environments to notice the API changes, the unknown fields are
+// ignored by default for forward compatibility.
+func WithStrictResponseDecoding() Option {
+	return func(p *Pushover) {
+		p.strictDecoding = true
+	}
+}
+
+// WithJSONTransport makes the app send the messages as JSON, with the
+// attachment encoded in base64. This is meant to be used with the Pushover
+// compatible gateways only accepting JSON, the Pushover API is used with url
+// encoded and multipart requests by default.
+func WithJSONTransport() Option {
+	return func(p *Pushover) {
+		p.jsonTransport = true
+	}
+}
+
+// WithEmergencyDefaults sets the retry and expire parameters of the emergency
+// messages sent without them. The values set on a message take precedence.
+func WithEmergencyDefaults(retry, expire time.Duration) Option {
+	return func(p *Pushover) {
+		p.emergencyRetry = retry
+		p.emergencyExpire = expire
+	}
+}
+
+// WithDefaultTitle sets the title of the messages sent without one. The
+// title set on a message takes precedence.
+func WithDefaultTitle(title string) Option {
+	return func(p *Pushover) {
+		p.defaultTitle = title
+	}
+}
+
+// WithTitleFromFirstLine sets the title of the messages sent without one to
+// the first line of the message, truncated to the title length limit. The
+// message is sent in full. The title set on a message takes precedence.
+func WithTitleFromFirstLine() Option {
+	return func(p *Pushover) {
+		p.titleFromFirstLine = true
+	}
+}
+
+// WithContext sets a context to the app, all the pending and future requests
+// are aborted when this context is done. It's meant to be used as a service
+// wide shutdown signal, along with the per request contexts.
+func WithContext(ctx context.Context) Option {
+	return func(p *Pushover) {
+		p.ctx = ctx
+	}
+}
+
+// WithCaptureLastRequest keeps a dump of the last request sent to the API,
+// available with LastRequestDump. This is meant for debugging, the requests
+// are not captured by default to avoid the memory overhead.
+func WithCaptureLastRequest() Option {
+	return func(p *Pushover) {
+		p.captureLastRequest = true
+	}
+}
+
+// WithCaptureRawResponse keeps the raw body of the responses in their Raw
+// field, it's not kept by default to save the memory.
+func WithCaptureRawResponse() Option {
+	return func(p *Pushover) {
+		p.captureRawResponse = true
+	}
+}
+
+// WithMultipartBoundary sets the boundary of the multipart requests used to
+// send the attachments, instead of a random one. This is meant to get
+// reproducible requests in tests.
+func WithMultipartBoundary(boundary string) Option {
+	return func(p *Pushover) {
+		p.multipartBoundary = boundary
+	}
+}
+
+// WithRetries retries the requests failing because of a server or a network
+// error, up to maxRetries times. The delay between the attempts starts at the
+// backoff duration and doubles after each attempt. The requests are not
+// retried by default.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(p *Pushover) {
+		p.maxRetries = maxRetries
+		p.retryBackoff = backoff
+	}
+}
+
+// WithBackoffJitter randomly reduces the delay between the retries by up to
+// the given fraction of the delay, between 0 and 1, to avoid many clients
+// retrying at the same time. A fraction of 1 is a full jitter.
+func WithBackoffJitter(fraction float64) Option {
+	return func(p *Pushover) {
+		switch {
+		case fraction < 0:
+			fraction = 0
+		case fraction > 1:
+			fraction = 1
+		}
+		p.backoffJitter = fraction
+	}
+}
+
+// WithValidator adds a custom validation to the messages, run after the
+// built-in validation. The error returned by the validator is returned by
+// the send, this is meant to enforce an organization's policies.
+func WithValidator(validator func(*Message) error) Option {
+	return func(p *Pushover) {
+		p.validators = append(p.validators, validator)
+	}
+}
+
+// WithClock sets the clock used by the app instead of time.Now, this is
+// meant to be used in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(p *Pushover) {
+		p.clock = clock
+	}
+}
+
+// WithAutoTimestamp sets the timestamp of the messages sent without one to
+// the send time, so the notifications show the time of the event even if
+// their delivery is delayed.
+func WithAutoTimestamp() Option {
+	return func(p *Pushover) {
+		p.autoTimestamp = true
+	}
+}
+
+// WithStrictValidation keeps the devices of the recipients checked with
+// GetRecipientDetails and rejects the messages targeting another device of
+// these recipients with an ErrUnknownDevice. The messages with a sound that
+// is neither built-in nor fetched with GetSounds are rejected with an
+// ErrUnknownSound.
+func WithStrictValidation() Option {
+	return func(p *Pushover) {
+		p.strictValidation = true
+	}
+}
+
+// WithMaxMessageLength overrides the MessageMaxLength limit, to follow a
+// server side limit raise without waiting for a new release.
+func WithMaxMessageLength(length int) Option {
+	return func(p *Pushover) {
+		p.messageLimit = length
+	}
+}
+
+// WithMaxTitleLength overrides the MessageTitleMaxLength limit, to follow a
+// server side limit raise without waiting for a new release.
+func WithMaxTitleLength(length int) Option {
+	return func(p *Pushover) {
+		p.titleLimit = length
+	}
+}
+
+// WithBatchRetryBudget bounds the total number of retries of a bulk send, so
+// that the recipients failing during an API outage don't retry each on their
+// own. The retries of each send are still bounded by WithRetries.
+func WithBatchRetryBudget(n int) Option {
+	return func(p *Pushover) {
+		p.batchRetryBudget = n
+	}
+}
+
+// WithOptimisticQuota makes CanSend return true while the quota is unknown,
+// before any message is sent.
+func WithOptimisticQuota() Option {
+	return func(p *Pushover) {
+		p.optimisticQuota = true
+	}
+}
+
+// WithQuotaCircuitBreaker rejects the messages with an ErrQuotaExceeded,
+// without calling the API, while the quota known from the server is
+// exhausted, until its reset time. The emergency messages are always sent.
+func WithQuotaCircuitBreaker() Option {
+	return func(p *Pushover) {
+		p.quotaBreaker = true
+	}
+}
+
+// WithDefaultRecipient sets the recipient of the messages sent with a nil
+// recipient.
+func WithDefaultRecipient(recipient *Recipient) Option {
+	return func(p *Pushover) {
+		p.defaultRecipient = recipient
+	}
+}
+
+// WithTimeout bounds the duration of each request, including its retries.
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Pushover) {
+		p.timeout = timeout
+	}
+}
+
+// WithNormalizeNewlines replaces the \r\n and \r line endings of the
+// messages with \n, e.g. for a text coming from Windows.
+func WithNormalizeNewlines() Option {
+	return func(p *Pushover) {
+		p.normalizeNewlines = true
+	}
+}
+
+// WithoutValidation skips the built-in validation of the messages, deferring
+// it to the API, for the messages already validated upstream. The app token
+// and the user keys are still checked, the validators added WithValidator
+// still run.
+func WithoutValidation() Option {
+	return func(p *Pushover) {
+		p.skipValidation = true
+	}
+}
+
+// WithAttachmentFallback sends a message again without its attachment if the
+// API, e.g. a gateway, responds that the attachments aren't supported. The
+// response then has its AttachmentDropped flag set.
+func WithAttachmentFallback() Option {
+	return func(p *Pushover) {
+		p.attachmentFallback = true
+	}
+}
+
+// WithSoundFallback sets the sounds to try in order when the message sound is
+// missing from the sounds fetched with GetSounds, e.g. a custom sound the
+// user removed. The sounds are left untouched until GetSounds is called.
+func WithSoundFallback(sounds []string) Option {
+	return func(p *Pushover) {
+		p.soundFallback = sounds
+	}
+}
+
+// WithAutoDowngrade sends the high priority messages with a normal priority
+// while the remaining quota known from the last message sent is under the
+// threshold, the response then has its Downgraded flag set. The emergency
+// messages are never downgraded.
+func WithAutoDowngrade(threshold int) Option {
+	return func(p *Pushover) {
+		p.downgradeThreshold = threshold
+	}
+}
+
+// WithMaxBackoff caps the delay between two retries, 30s by default.
+func WithMaxBackoff(d time.Duration) Option {
+	return func(p *Pushover) {
+		p.maxBackoff = d
+	}
+}
+
+// WithDefaultDevice sets the device targeted by the messages sent without
+// any device, e.g. for an app always notifying a single display.
+func WithDefaultDevice(device string) Option {
+	return func(p *Pushover) {
+		p.defaultDevice = device
+	}
+}
+
+// WithMetricsCollector sets the collector observing each message sent to the
+// API.
+func WithMetricsCollector(collector MetricsCollector) Option {
+	return func(p *Pushover) {
+		p.metrics = collector
+	}
+}
+
+// WithInsecureTLS disables the verification of the server certificates, e.g.
+// to go through a local proxy with a self-signed certificate. This is unsafe
+// and meant to be used in development only, never in production.
+func WithInsecureTLS() Option {
+	return func(p *Pushover) {
+		p.httpTransport().TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+}
+
+// WithRetryPredicate sets the function deciding if a request is retried
+// after an error, instead of retrying the server and network errors only.
+// The retries are still bounded by WithRetries.
+func WithRetryPredicate(retryable func(error) bool) Option {
+	return func(p *Pushover) {
+		p.retryPredicate = retryable
+	}
+}
+
+// WithTitlePrefix prefixes the title of the messages, e.g. with the host
+// name of a server. The original title is truncated to keep the title within
+// its length limit.
+func WithTitlePrefix(prefix string) Option {
+	return func(p *Pushover) {
+		p.titlePrefix = prefix
+	}
+}
+
+// WithFallbackRecipient sets the recipient, e.g. a team group, of the
+// messages rejected by the API for their recipient, e.g. a disabled user.
+// The response then has its FallbackUsed flag set. The messages rejected for
+// another reason, e.g. a too long message, are not sent to it.
+func WithFallbackRecipient(recipient *Recipient) Option {
+	return func(p *Pushover) {
+		p.fallbackRecipient = recipient
+	}
+}
+
+// WithQuietHours sends the messages with the lowest priority, i.e. without
+// any notification, during the daily quiet hours. The start and the end are
+// durations since midnight in the given location, e.g. 22*time.Hour and
+// 7*time.Hour for the night. The emergency messages always go through.
+func WithQuietHours(start, end time.Duration, location *time.Location) Option {
+	return func(p *Pushover) {
+		if location == nil {
+			location = time.Local
+		}
+		p.quietHours = &quietHours{start: start, end: end, location: location}
+	}
+}
+
+// WithAppName sets the name of the app returned by AppName, e.g. to display
+// it in a UI. It's not sent to the API.
+func WithAppName(name string) Option {
+	return func(p *Pushover) {
+		p.appName = name
+	}
+}
+
+// WithDedupeWindow swallows the messages identical to a message sent to the
+// same recipient within the window, e.g. the duplicate alerts of a retried
+// job. The response then has its Deduped flag set. The messages are compared
+// by their title, message, priority and devices.
+func WithDedupeWindow(d time.Duration) Option {
+	return func(p *Pushover) {
+		p.dedupeWindow = d
+		if p.dedupeStore == nil {
+			p.dedupeStore = &memoryDedupeStore{now: p.now}
+		}
+	}
+}
+
+// WithDedupeStore sets the store of the messages recently sent, instead of
+// an in-memory store, e.g. to dedupe the messages across processes.
+func WithDedupeStore(store DedupeStore) Option {
+	return func(p *Pushover) {
+		p.dedupeStore = store
+	}
+}
+
+// WithMaxConcurrency sets the maximum number of concurrent sends of a bulk
+// send, 4 by default. A value of 1 sends the messages one after the other.
+func WithMaxConcurrency(n int) Option {
+	return func(p *Pushover) {
+		p.maxConcurrency = n
+	}
+}
+
+// WithSlowSendThreshold calls the hook with the latency of the sends slower
+// than the threshold, e.g. to alert when the API responds slowly. The hook is
+// called synchronously once the send is done, successful or not.
+func WithSlowSendThreshold(threshold time.Duration, hook func(latency time.Duration, message *Message)) Option {
+	return func(p *Pushover) {
+		p.slowSendThreshold = threshold
+		p.onSlowSend = hook
+	}
+}
+
+// WithForceHTTP2 attempts HTTP/2 even with a custom TLS config, e.g. set by
+// WithInsecureTLS, to multiplex the requests over a single connection. The
+// requests are sent with HTTP/1.1 with a custom TLS config otherwise, and
+// fall back to HTTP/1.1 if the server doesn't support HTTP/2.
+func WithForceHTTP2() Option {
+	return func(p *Pushover) {
+		p.httpTransport().ForceAttemptHTTP2 = true
+	}
+}
+
+// WithEmojiShortcodes expands the common emoji shortcodes, e.g. :warning: or
+// :fire:, in the title and the message. They're expanded before the length
+// validation, which counts the emoji rather than the shortcodes.
+func WithEmojiShortcodes() Option {
+	return func(p *Pushover) {
+		p.emojiShortcodes = true
+	}
+}
+
+// WithStripANSI removes the ANSI escape sequences, e.g. the colors of a
+// program output, from the messages. They're removed before the length
+// validation.
+func WithStripANSI() Option {
+	return func(p *Pushover) {
+		p.stripANSI = true
+	}
+}
+
+// WithDefaultPriority sets the priority of the messages created with
+// NewMessage and NewMessageWithTitle, e.g. high for a critical only
+// integration. The messages with an explicit priority, including normal, keep
+// it. An emergency default priority needs WithEmergencyDefaults.
+func WithDefaultPriority(priority int) Option {
+	return func(p *Pushover) {
+		p.defaultPriority = priority
+	}
+}
+
+// WithPreflightValidation validates the recipients with the API before the
+// first message sent to them, the send fails with an ErrInvalidRecipient if
+// the recipient isn't valid. The result is cached for each recipient.
+func WithPreflightValidation() Option {
+	return func(p *Pushover) {
+		p.preflightValidation = true
+	}
+}
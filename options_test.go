@@ -0,0 +1,1206 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Receipt details response with an unknown field
+const fakeReceiptBody = `{"status":1,"acknowledged":0,"acknowledged_at":0,"last_delivered_at":0,` +
+	`"expired":0,"expires_at":0,"called_back":0,"called_back_at":0,` +
+	`"request":"e95f35c2d75a100a3719b3764f0c8e47","new_field":"value"}`
+
+// TestStrictResponseDecoding tests the behavior with unknown response fields
+func TestStrictResponseDecoding(t *testing.T) {
+	tt := []struct {
+		name        string
+		body        string
+		opts        []Option
+		call        func(p *Pushover) error
+		expectedErr error
+		expectErr   bool
+	}{
+		{
+			name: "default decoding",
+			body: `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","new_field":"value"}`,
+			call: func(p *Pushover) error {
+				_, err := p.CancelEmergencyNotification("receipt")
+				return err
+			},
+		},
+		{
+			name: "strict decoding",
+			body: `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","new_field":"value"}`,
+			opts: []Option{WithStrictResponseDecoding()},
+			call: func(p *Pushover) error {
+				_, err := p.CancelEmergencyNotification("receipt")
+				return err
+			},
+			expectErr: true,
+		},
+		{
+			name: "strict decoding of an error response",
+			body: `{"user":"invalid","errors":["user identifier is invalid"],"status":0,"request":"e460545a8b333d0da2f3602aff3133d6"}`,
+			opts: []Option{WithStrictResponseDecoding()},
+			call: func(p *Pushover) error {
+				_, err := p.SendMessage(NewMessage("Hello"), fakeRecipient)
+				return err
+			},
+			expectedErr: Errors{"user identifier is invalid"},
+			expectErr:   true,
+		},
+		{
+			name: "default decoding of receipt details",
+			body: fakeReceiptBody,
+			call: func(p *Pushover) error {
+				_, err := p.GetReceiptDetails("receipt")
+				return err
+			},
+		},
+		{
+			name: "strict decoding of receipt details",
+			body: fakeReceiptBody,
+			opts: []Option{WithStrictResponseDecoding()},
+			call: func(p *Pushover) error {
+				_, err := p.GetReceiptDetails("receipt")
+				return err
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, tc.body)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			err := tc.call(New(fakePushover.token, tc.opts...))
+			if gotErr := err != nil; gotErr != tc.expectErr {
+				t.Fatalf("expected error: %t, got %v", tc.expectErr, err)
+			}
+
+			if tc.expectedErr != nil && !reflect.DeepEqual(err, tc.expectedErr) {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestEmergencyDefaults tests the emergency parameters defaults
+func TestEmergencyDefaults(t *testing.T) {
+	tt := []struct {
+		name           string
+		opts           []Option
+		message        *Message
+		expectedRetry  time.Duration
+		expectedExpire time.Duration
+	}{
+		{
+			name:    "no defaults",
+			message: &Message{Message: "Hello", Priority: PriorityEmergency},
+		},
+		{
+			name:           "with defaults",
+			opts:           []Option{WithEmergencyDefaults(time.Minute, 2*time.Hour)},
+			message:        &Message{Message: "Hello", Priority: PriorityEmergency},
+			expectedRetry:  time.Minute,
+			expectedExpire: 2 * time.Hour,
+		},
+		{
+			name: "with defaults overridden by the message",
+			opts: []Option{WithEmergencyDefaults(time.Minute, 2*time.Hour)},
+			message: &Message{
+				Message:  "Hello",
+				Priority: PriorityEmergency,
+				Retry:    30 * time.Second,
+				Expire:   time.Hour,
+			},
+			expectedRetry:  30 * time.Second,
+			expectedExpire: time.Hour,
+		},
+		{
+			name:    "with defaults on a normal message",
+			opts:    []Option{WithEmergencyDefaults(time.Minute, 2*time.Hour)},
+			message: &Message{Message: "Hello", Priority: PriorityNormal},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(tc.message)
+			if got.Retry != tc.expectedRetry || got.Expire != tc.expectedExpire {
+				t.Fatalf("expected retry %s and expire %s, got %s and %s",
+					tc.expectedRetry, tc.expectedExpire, got.Retry, got.Expire)
+			}
+		})
+	}
+}
+
+// TestEmergencyDefaultsSend tests that an emergency message without
+// parameters can be sent with the emergency defaults
+func TestEmergencyDefaultsSend(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	message := &Message{Message: "Hello", Priority: PriorityEmergency}
+
+	if _, err := fakePushover.SendMessage(message, fakeRecipient); err != ErrMissingEmergencyParameter {
+		t.Fatalf("expected %v, got %v", ErrMissingEmergencyParameter, err)
+	}
+
+	p := New(fakePushover.token, WithEmergencyDefaults(time.Minute, 2*time.Hour))
+	if _, err := p.SendMessage(message, fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if message.Retry != 0 || message.Expire != 0 {
+		t.Fatalf("the original message should not be modified")
+	}
+}
+
+// TestDefaultTitle tests the default title
+func TestDefaultTitle(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     []Option
+		title    string
+		expected string
+	}{
+		{"no default title", nil, "", ""},
+		{"default title", []Option{WithDefaultTitle("My app")}, "", "My app"},
+		{"default title overridden", []Option{WithDefaultTitle("My app")}, "Title", "Title"},
+		{"app default title", []Option{WithDefaultTitle("My app")}, UseAppDefaultTitle, ""},
+		{"app default title with prefix", []Option{WithTitlePrefix("[web-1] ")}, UseAppDefaultTitle, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(NewMessageWithTitle("Hello", tc.title))
+			if got.Title != tc.expected {
+				t.Fatalf("expected title %q, got %q", tc.expected, got.Title)
+			}
+		})
+	}
+}
+
+// TestTitleFromFirstLine tests the title taken from the first line of the
+// message
+func TestTitleFromFirstLine(t *testing.T) {
+	long := strings.Repeat("x", MessageTitleMaxLength+10)
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		title    string
+		message  string
+		expected string
+	}{
+		{"first line", []Option{WithTitleFromFirstLine()}, "", "Disk full\nOnly 2% left on /var", "Disk full"},
+		{"single line", []Option{WithTitleFromFirstLine()}, "", "Disk full", "Disk full"},
+		{"windows newlines", []Option{WithTitleFromFirstLine()}, "", " Disk full \r\nOnly 2% left", "Disk full"},
+		{"truncated", []Option{WithTitleFromFirstLine()}, "", long + "\nDetails", long[:MessageTitleMaxLength]},
+		{"explicit title", []Option{WithTitleFromFirstLine()}, "Title", "Disk full\nDetails", "Title"},
+		{"empty first line", []Option{WithTitleFromFirstLine(), WithDefaultTitle("My app")}, "", "\nDetails", "My app"},
+		{"app default title", []Option{WithTitleFromFirstLine()}, UseAppDefaultTitle, "Disk full\nDetails", ""},
+		{"without the option", nil, "", "Disk full\nDetails", ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(NewMessageWithTitle(tc.message, tc.title))
+			if got.Title != tc.expected {
+				t.Fatalf("expected title %q, got %q", tc.expected, got.Title)
+			}
+
+			if got.Message != tc.message {
+				t.Fatalf("expected the full message %q, got %q", tc.message, got.Message)
+			}
+		})
+	}
+}
+
+// TestContext tests that the requests are aborted when the app context is
+// done
+func TestContext(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(fakePushover.token, WithContext(ctx))
+
+	if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	cancel()
+	if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	results, summary := p.SendToMany(NewMessage("Hello"), []*Recipient{fakeRecipient, fakeRecipient})
+	for _, result := range results {
+		if result.Err != context.Canceled {
+			t.Fatalf("expected %v, got %v", context.Canceled, result.Err)
+		}
+	}
+
+	if summary.Failed != 2 {
+		t.Fatalf("expected 2 failures, got %d", summary.Failed)
+	}
+}
+
+// TestContextInFlight tests that an in-flight request is aborted when the app
+// context is done
+func TestContextInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-unblock
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	APIEndpoint = ts.URL
+	p := New(fakePushover.token, WithContext(ctx))
+
+	_, err := p.SendMessageContext(context.Background(), NewMessage("Hello"), fakeRecipient)
+	urlErr, ok := err.(*url.Error)
+	if !ok || urlErr.Err != context.Canceled {
+		t.Fatalf("expected a canceled request, got %v", err)
+	}
+}
+
+// TestJSONTransport tests that the messages are sent as JSON with the JSON
+// transport
+func TestJSONTransport(t *testing.T) {
+	tt := []struct {
+		name                string
+		opts                []Option
+		expectedContentType string
+	}{
+		{"default transport", nil, "application/x-www-form-urlencoded"},
+		{"JSON transport", []Option{WithJSONTransport()}, "application/json"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var contentType string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				w.Header().Set("X-Limit-App-Limit", "7500")
+				w.Header().Set("X-Limit-App-Remaining", "6000")
+				w.Header().Set("X-Limit-App-Reset", "1393653600")
+				fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if contentType != tc.expectedContentType {
+				t.Fatalf("expected content type %q, got %q", tc.expectedContentType, contentType)
+			}
+		})
+	}
+}
+
+// TestCaptureRawResponse tests the raw body kept in the responses
+func TestCaptureRawResponse(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	response, err := fakePushover.SendMessage(NewMessage("Hello"), fakeRecipient)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if response.Raw != nil {
+		t.Fatalf("expected no raw body without the option, got %q", response.Raw)
+	}
+
+	p := New(fakePushover.token, WithCaptureRawResponse())
+	tt := []struct {
+		name      string
+		recipient *Recipient
+		expected  string
+	}{
+		{"success", fakeRecipient, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`},
+		{"error", fakeInvalidRecipient, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			response, _ := p.SendMessage(NewMessage("Hello"), tc.recipient)
+			if got := strings.TrimSpace(string(response.Raw)); got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestCaptureLastRequest tests the capture of the last request
+func TestCaptureLastRequest(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	if dump := fakePushover.LastRequestDump(); dump != nil {
+		t.Fatalf("expected no dump without the option, got %q", dump)
+	}
+
+	p := New(fakePushover.token, WithCaptureLastRequest())
+	if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	dump := string(p.LastRequestDump())
+	for _, expected := range []string{"POST /messages.json", "message=Hello", "token=" + redactedSecret} {
+		if !strings.Contains(dump, expected) {
+			t.Fatalf("expected %q in the dump %q", expected, dump)
+		}
+	}
+
+	for _, secret := range []string{fakePushover.token, fakeRecipient.token} {
+		if strings.Contains(dump, secret) {
+			t.Fatalf("the dump %q contains a secret", dump)
+		}
+	}
+}
+
+// TestMultipartBoundary tests the multipart boundary option
+func TestMultipartBoundary(t *testing.T) {
+	boundary := "pushover-test-boundary"
+	var contentType, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	message := NewMessage("Hello")
+	message.AddAttachment(strings.NewReader("attachment"))
+
+	p := New(fakePushover.token, WithMultipartBoundary(boundary))
+	if _, err := p.SendMessage(message, fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if expected := "multipart/form-data; boundary=" + boundary; contentType != expected {
+		t.Fatalf("expected content type %q, got %q", expected, contentType)
+	}
+
+	if !strings.HasSuffix(body, "--"+boundary+"--\r\n") {
+		t.Fatalf("expected the body to end with the boundary, got %q", body)
+	}
+}
+
+// TestValidator tests the custom validators
+func TestValidator(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	errMissingRunbook := errors.New("emergency messages require a runbook URL")
+	requireRunbook := func(m *Message) error {
+		if m.Priority == PriorityEmergency && m.URL == "" {
+			return errMissingRunbook
+		}
+		return nil
+	}
+
+	calls := 0
+	countCalls := func(m *Message) error {
+		calls++
+		return nil
+	}
+
+	tt := []struct {
+		name          string
+		message       *Message
+		err           error
+		expectedCalls int
+	}{
+		{"valid message", NewMessage("Hello"), nil, 1},
+		{"invalid message", NewEmergencyMessage("Hello", time.Minute, time.Hour), errMissingRunbook, 0},
+		{"built-in validation first", NewEmergencyMessage("Hello", 0, 0), ErrMissingEmergencyParameter, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = 0
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, WithValidator(requireRunbook), WithValidator(countCalls))
+			if _, err := p.SendMessage(tc.message, fakeRecipient); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if calls != tc.expectedCalls {
+				t.Fatalf("expected %d calls to the second validator, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+// TestAutoTimestamp tests the automatic timestamp
+func TestAutoTimestamp(t *testing.T) {
+	now := time.Unix(1393653600, 0)
+	clock := func() time.Time { return now }
+
+	tt := []struct {
+		name      string
+		opts      []Option
+		timestamp int64
+		expected  int64
+	}{
+		{"no auto timestamp", []Option{WithClock(clock)}, 0, 0},
+		{"auto timestamp", []Option{WithClock(clock), WithAutoTimestamp()}, 0, now.Unix()},
+		{"explicit timestamp", []Option{WithClock(clock), WithAutoTimestamp()}, 1424305421, 1424305421},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(&Message{Message: "Hello", Timestamp: tc.timestamp})
+			if got.Timestamp != tc.expected {
+				t.Fatalf("expected timestamp %d, got %d", tc.expected, got.Timestamp)
+			}
+		})
+	}
+}
+
+// TestStrictValidation tests the devices checked against the validated
+// recipient devices
+func TestStrictValidation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/users/validate.json") {
+			fmt.Fprintln(w, `{"status":1,"group":0,"devices":["iphone","desktop"],"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		validate bool
+		device   string
+		err      error
+	}{
+		{"known device", []Option{WithStrictValidation()}, true, "iphone", nil},
+		{"unknown device", []Option{WithStrictValidation()}, true, "android", ErrUnknownDevice},
+		{"all devices", []Option{WithStrictValidation()}, true, "", nil},
+		{"recipient not validated", []Option{WithStrictValidation()}, false, "android", nil},
+		{"not strict", nil, true, "android", nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			if tc.validate {
+				if _, err := p.GetRecipientDetails(fakeRecipient); err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+			}
+
+			message := NewMessage("Hello")
+			message.DeviceName = tc.device
+			if _, err := p.SendMessage(message, fakeRecipient); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestMaxLengths tests the configurable message and title length limits
+func TestMaxLengths(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	longMessage := strings.Repeat("a", MessageMaxLength+1)
+	longTitle := strings.Repeat("a", MessageTitleMaxLength+1)
+
+	tt := []struct {
+		name    string
+		opts    []Option
+		message *Message
+		err     error
+	}{
+		{"default message limit", nil, NewMessage(longMessage), ErrMessageTooLong},
+		{"raised message limit", []Option{WithMaxMessageLength(2048)}, NewMessage(longMessage), nil},
+		{"lowered message limit", []Option{WithMaxMessageLength(10)}, NewMessage("Hello world !"), ErrMessageTooLong},
+		{"default title limit", nil, NewMessageWithTitle("Hello", longTitle), ErrMessageTitleTooLong},
+		{"raised title limit", []Option{WithMaxTitleLength(512)}, NewMessageWithTitle("Hello", longTitle), nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			if _, err := p.SendMessage(tc.message, fakeRecipient); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestNormalizeNewlines tests the line endings normalization
+func TestNormalizeNewlines(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     []Option
+		message  string
+		expected string
+	}{
+		{"not normalized", nil, "a\r\nb", "a\r\nb"},
+		{"windows", []Option{WithNormalizeNewlines()}, "a\r\nb\r\n", "a\nb\n"},
+		{"old mac", []Option{WithNormalizeNewlines()}, "a\rb", "a\nb"},
+		{"mixed", []Option{WithNormalizeNewlines()}, "a\r\n\rb\n", "a\n\nb\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			message := NewMessage(tc.message)
+			if got := p.prepareMessage(message).Message; got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+
+			if message.Message != tc.message {
+				t.Fatalf("the original message should be left untouched")
+			}
+		})
+	}
+}
+
+// TestWithoutValidation tests that the messages validation is skipped
+func TestWithoutValidation(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	tooLong := NewMessage(strings.Repeat("a", MessageMaxLength+1))
+
+	p := New(fakePushover.token)
+	if _, err := p.SendMessage(tooLong, fakeRecipient); err != ErrMessageTooLong {
+		t.Fatalf("expected %v, got %v", ErrMessageTooLong, err)
+	}
+
+	p = New(fakePushover.token, WithoutValidation())
+	if _, err := p.SendMessage(tooLong, fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := p.SendMessage(tooLong, NewRecipient("invalid")); err != ErrInvalidRecipientToken {
+		t.Fatalf("expected %v, got %v", ErrInvalidRecipientToken, err)
+	}
+}
+
+// TestAttachmentFallback tests the message sent again without attachment
+func TestAttachmentFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["attachments are not supported"]}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	tt := []struct {
+		name            string
+		opts            []Option
+		expectErr       bool
+		expectedDropped bool
+	}{
+		{"no fallback", nil, true, false},
+		{"fallback", []Option{WithAttachmentFallback()}, false, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			message := NewMessage("Hello")
+			message.AddAttachment(strings.NewReader("fake image"))
+
+			response, err := p.SendMessage(message, fakeRecipient)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %t, got %v", tc.expectErr, err)
+			}
+
+			if response.AttachmentDropped != tc.expectedDropped {
+				t.Fatalf("expected attachment dropped %t, got %t", tc.expectedDropped, response.AttachmentDropped)
+			}
+
+			if message.attachment == nil {
+				t.Fatalf("the original message should keep its attachment")
+			}
+		})
+	}
+}
+
+// TestDefaultDevice tests the default device of the messages
+func TestDefaultDevice(t *testing.T) {
+	p := New(fakePushover.token, WithDefaultDevice("kiosk"))
+
+	tt := []struct {
+		name     string
+		message  *Message
+		expected string
+	}{
+		{"no device", &Message{Message: "Hello"}, "kiosk"},
+		{"device name", &Message{Message: "Hello", DeviceName: "iphone"}, "iphone"},
+		{"devices", &Message{Message: "Hello", Devices: []Device{"iphone"}}, "iphone"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := p.prepareMessage(tc.message).toMap("pToken", "rToken")["device"]
+			if got != tc.expected {
+				t.Fatalf("expected device %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestInsecureTLS tests the requests to a server with a self-signed
+// certificate
+func TestInsecureTLS(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	// The rejected handshake is expected
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	if _, err := New(fakePushover.token).SendMessage(NewMessage("Hello"), fakeRecipient); err == nil {
+		t.Fatalf("expected a certificate error, got nil")
+	}
+
+	if _, err := New(fakePushover.token, WithInsecureTLS()).SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if c := http.DefaultTransport.(*http.Transport).TLSClientConfig; c != nil && c.InsecureSkipVerify {
+		t.Fatalf("the default transport should be left untouched")
+	}
+}
+
+// TestTitlePrefix tests the prefixed titles
+func TestTitlePrefix(t *testing.T) {
+	prefix := "[prod-web-1] "
+	longTitle := strings.Repeat("é", MessageTitleMaxLength)
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		title    string
+		expected string
+	}{
+		{"title", nil, "Disk full", "[prod-web-1] Disk full"},
+		{"empty title", nil, "", "[prod-web-1]"},
+		{"default title", []Option{WithDefaultTitle("Monitoring")}, "", "[prod-web-1] Monitoring"},
+		{"truncated title", nil, longTitle, prefix + longTitle[:2*(MessageTitleMaxLength-len(prefix))]},
+		{"raised limit", []Option{WithMaxTitleLength(300)}, longTitle, prefix + longTitle},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, append(tc.opts, WithTitlePrefix(prefix))...)
+			got := p.prepareMessage(NewMessageWithTitle("Hello", tc.title))
+			if got.Title != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got.Title)
+			}
+
+			if err := got.validateWithLimits(p.maxLength(), p.maxTitleLength()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestFallbackRecipient tests the messages sent to the fallback recipient
+func TestFallbackRecipient(t *testing.T) {
+	var users, attachments []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users = append(users, r.FormValue("user"))
+		if f, _, err := r.FormFile("attachment"); err == nil {
+			b, _ := io.ReadAll(f)
+			attachments = append(attachments, string(b))
+		}
+
+		if r.FormValue("user") == fakeInvalidRecipient.token {
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+			return
+		}
+
+		if r.FormValue("message") == "Rejected" {
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","message":"is invalid","errors":["message is invalid"]}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	anotherRecipient := NewRecipient("uQiRzpo4DXghDmr9QzzfQu27cmVRsG")
+
+	tt := []struct {
+		name             string
+		opts             []Option
+		message          string
+		recipient        *Recipient
+		expectedUsers    []string
+		expectedFallback bool
+		expectErr        bool
+	}{
+		{"valid recipient", []Option{WithFallbackRecipient(fakeRecipient)}, "Hello", fakeRecipient, []string{fakeRecipient.token}, false, false},
+		{"rejected recipient", []Option{WithFallbackRecipient(fakeRecipient)}, "Hello", fakeInvalidRecipient, []string{fakeInvalidRecipient.token, fakeRecipient.token}, true, false},
+		{"rejected message", []Option{WithFallbackRecipient(anotherRecipient)}, "Rejected", fakeRecipient, []string{fakeRecipient.token}, false, true},
+		{"rejected fallback", []Option{WithFallbackRecipient(fakeInvalidRecipient)}, "Hello", fakeInvalidRecipient, []string{fakeInvalidRecipient.token}, false, true},
+		{"no fallback", nil, "Hello", fakeInvalidRecipient, []string{fakeInvalidRecipient.token}, false, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			users, attachments = nil, nil
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			message := NewMessage(tc.message)
+			message.AddAttachment(strings.NewReader("fake image"))
+
+			response, err := p.SendMessage(message, tc.recipient)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %t, got %v", tc.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(users, tc.expectedUsers) {
+				t.Fatalf("expected users %v, got %v", tc.expectedUsers, users)
+			}
+
+			for _, attachment := range attachments {
+				if attachment != "fake image" {
+					t.Fatalf("expected the attachment on each send, got %q", attachment)
+				}
+			}
+
+			if response.FallbackUsed != tc.expectedFallback {
+				t.Fatalf("expected fallback used %t, got %t", tc.expectedFallback, response.FallbackUsed)
+			}
+		})
+	}
+}
+
+// TestQuietHours tests the priority of the messages sent during the quiet
+// hours
+func TestQuietHours(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("missing time zone database")
+	}
+
+	night := WithQuietHours(22*time.Hour, 7*time.Hour, paris)
+	lunch := WithQuietHours(12*time.Hour, 14*time.Hour, paris)
+
+	tt := []struct {
+		name     string
+		quiet    Option
+		now      time.Time
+		priority int
+		expected int
+	}{
+		{"night before midnight", night, time.Date(2026, 10, 16, 23, 0, 0, 0, paris), PriorityNormal, PriorityLowest},
+		{"night after midnight", night, time.Date(2026, 10, 16, 3, 0, 0, 0, paris), PriorityHigh, PriorityLowest},
+		{"night in UTC", night, time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC), PriorityNormal, PriorityLowest},
+		{"morning", night, time.Date(2026, 10, 16, 7, 0, 0, 0, paris), PriorityNormal, PriorityNormal},
+		{"emergency", night, time.Date(2026, 10, 16, 3, 0, 0, 0, paris), PriorityEmergency, PriorityEmergency},
+		{"lunch", lunch, time.Date(2026, 10, 16, 13, 0, 0, 0, paris), PriorityLow, PriorityLowest},
+		{"afternoon", lunch, time.Date(2026, 10, 16, 15, 0, 0, 0, paris), PriorityLow, PriorityLow},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			now := tc.now
+			p := New(fakePushover.token, tc.quiet, WithClock(func() time.Time { return now }))
+
+			message := NewMessage("Hello")
+			message.Priority = tc.priority
+			if got := p.prepareMessage(message).Priority; got != tc.expected {
+				t.Fatalf("expected priority %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestAppName tests the name of the app
+func TestAppName(t *testing.T) {
+	if got := fakePushover.AppName(); got != "" {
+		t.Fatalf("expected an empty name, got %q", got)
+	}
+
+	p := New(fakePushover.token, WithAppName("Alerts"))
+	if got := p.AppName(); got != "Alerts" {
+		t.Fatalf("expected %q, got %q", "Alerts", got)
+	}
+}
+
+// TestDedupeWindow tests the duplicate messages swallowed within the window
+func TestDedupeWindow(t *testing.T) {
+	var fail int32
+	var sent int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		atomic.AddInt32(&sent, 1)
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	now := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)
+	p := New(fakePushover.token, WithDedupeWindow(time.Minute), WithClock(func() time.Time { return now }))
+
+	highPriority := NewMessage("Disk full")
+	highPriority.Priority = PriorityHigh
+
+	tt := []struct {
+		name            string
+		message         *Message
+		recipient       *Recipient
+		elapsed         time.Duration
+		fail            bool
+		expectedDeduped bool
+	}{
+		{"first message", NewMessage("Disk full"), fakeRecipient, 0, false, false},
+		{"duplicate", NewMessage("Disk full"), fakeRecipient, 30 * time.Second, false, true},
+		{"other recipient", NewMessage("Disk full"), fakeInvalidRecipient, 0, false, false},
+		{"other priority", highPriority, fakeRecipient, 0, false, false},
+		{"other title", NewMessageWithTitle("Disk full", "web-1"), fakeRecipient, 0, false, false},
+		{"after the window", NewMessage("Disk full"), fakeRecipient, 31 * time.Second, false, false},
+		{"failed send", NewMessage("CPU hot"), fakeRecipient, 0, true, false},
+		{"after a failed send", NewMessage("CPU hot"), fakeRecipient, 0, false, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			now = now.Add(tc.elapsed)
+			if tc.fail {
+				atomic.StoreInt32(&fail, 1)
+				defer atomic.StoreInt32(&fail, 0)
+			}
+
+			before := atomic.LoadInt32(&sent)
+			response, err := p.SendMessage(tc.message, tc.recipient)
+			if tc.fail {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if response.Deduped != tc.expectedDeduped {
+				t.Fatalf("expected deduped %t, got %t", tc.expectedDeduped, response.Deduped)
+			}
+
+			if sent := atomic.LoadInt32(&sent) - before; (sent == 0) != tc.expectedDeduped {
+				t.Fatalf("unexpected %d messages sent", sent)
+			}
+		})
+	}
+}
+
+// TestForceHTTP2 tests the requests multiplexed over a single HTTP/2
+// connection, or sent with HTTP/1.1 without the option or if the server
+// doesn't support HTTP/2
+func TestForceHTTP2(t *testing.T) {
+	tt := []struct {
+		name          string
+		enableHTTP2   bool
+		opts          []Option
+		expectedProto int
+	}{
+		{"HTTP/2 server", true, []Option{WithForceHTTP2()}, 2},
+		{"HTTP/2 server without force", true, nil, 1},
+		{"HTTP/1.1 server", false, []Option{WithForceHTTP2()}, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			protos := map[int]bool{}
+			addrs := map[string]bool{}
+			ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				protos[r.ProtoMajor] = true
+				addrs[r.RemoteAddr] = true
+				mu.Unlock()
+
+				w.Header().Set("X-Limit-App-Limit", "7500")
+				w.Header().Set("X-Limit-App-Remaining", "6000")
+				w.Header().Set("X-Limit-App-Reset", "1393653600")
+				fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+			}))
+			ts.EnableHTTP2 = tc.enableHTTP2
+			ts.StartTLS()
+			defer ts.Close()
+			APIEndpoint = ts.URL
+
+			p := New(fakePushover.token, append([]Option{WithInsecureTLS()}, tc.opts...)...)
+			for i := 0; i < 3; i++ {
+				if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(protos) != 1 || !protos[tc.expectedProto] {
+				t.Fatalf("expected HTTP/%d only, got %v", tc.expectedProto, protos)
+			}
+
+			if len(addrs) != 1 {
+				t.Fatalf("expected the connection to be reused, got %d connections", len(addrs))
+			}
+		})
+	}
+}
+
+// TestEmojiShortcodes tests the shortcodes expanded before the validation
+func TestEmojiShortcodes(t *testing.T) {
+	fires := strings.Repeat(":fire:", 200)
+
+	tt := []struct {
+		name            string
+		opts            []Option
+		message         *Message
+		expectedTitle   string
+		expectedMessage string
+		expectedErr     error
+	}{
+		{
+			name:            "expanded",
+			opts:            []Option{WithEmojiShortcodes()},
+			message:         NewMessageWithTitle(":warning: Disk full :unknown:", ":fire: web-1"),
+			expectedTitle:   "\U0001F525 web-1",
+			expectedMessage: "⚠️ Disk full :unknown:",
+		},
+		{
+			name:            "default title",
+			opts:            []Option{WithEmojiShortcodes(), WithDefaultTitle(":bell: Alerts")},
+			message:         NewMessage("Hello"),
+			expectedTitle:   "\U0001F514 Alerts",
+			expectedMessage: "Hello",
+		},
+		{
+			name:            "without the option",
+			message:         NewMessage(":warning: Disk full"),
+			expectedMessage: ":warning: Disk full",
+		},
+		{
+			name:            "length of the emoji",
+			opts:            []Option{WithEmojiShortcodes()},
+			message:         NewMessage(fires),
+			expectedMessage: strings.Repeat("\U0001F525", 200),
+		},
+		{
+			name:            "length of the shortcodes",
+			message:         NewMessage(fires),
+			expectedMessage: fires,
+			expectedErr:     ErrMessageTooLong,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(tc.message)
+			if got.Title != tc.expectedTitle || got.Message != tc.expectedMessage {
+				t.Fatalf("expected %q %q, got %q %q", tc.expectedTitle, tc.expectedMessage, got.Title, got.Message)
+			}
+
+			if err := got.validateWithLimits(p.maxLength(), p.maxTitleLength()); err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestStripANSI tests the ANSI escape sequences removed before the
+// validation
+func TestStripANSI(t *testing.T) {
+	colored := "\x1b[31mFAIL\x1b[0m TestSend\n\x1b[1;32mok\x1b[m \x1b]0;title\x07pkg\x1b[2K"
+	long := strings.Repeat("\x1b[31mx\x1b[0m", MessageMaxLength)
+
+	tt := []struct {
+		name        string
+		opts        []Option
+		message     string
+		expected    string
+		expectedErr error
+	}{
+		{"colors", []Option{WithStripANSI()}, colored, "FAIL TestSend\nok pkg", nil},
+		{"stripped length", []Option{WithStripANSI()}, long, strings.Repeat("x", MessageMaxLength), nil},
+		{"without the option", nil, colored, colored, ErrInvalidControlChars},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(NewMessage(tc.message))
+			if got.Message != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got.Message)
+			}
+
+			if err := got.validateWithLimits(p.maxLength(), p.maxTitleLength()); err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestDefaultPriority tests the priority of the messages created without one
+func TestDefaultPriority(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     []Option
+		message  *Message
+		expected int
+	}{
+		{"no default priority", nil, NewMessage("Hello"), PriorityNormal},
+		{"default priority", []Option{WithDefaultPriority(PriorityHigh)}, NewMessageWithTitle("Hello", "Title"), PriorityHigh},
+		{"explicit normal priority", []Option{WithDefaultPriority(PriorityHigh)}, &Message{Message: "Hello"}, PriorityNormal},
+		{"explicit priority", []Option{WithDefaultPriority(PriorityHigh)}, NewLowPriorityMessage("Hello"), PriorityLow},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, tc.opts...)
+			got := p.prepareMessage(tc.message)
+			if got.Priority != tc.expected {
+				t.Fatalf("expected priority %d, got %d", tc.expected, got.Priority)
+			}
+
+			if got.toMap("", "")["priority"] != strconv.Itoa(tc.expected) {
+				t.Fatalf("expected priority %d to be sent, got %q", tc.expected, got.toMap("", "")["priority"])
+			}
+		})
+	}
+
+	// The messages sent without the app defaults have a normal priority
+	if got := NewMessage("Hello").toMap("", "")["priority"]; got != "0" {
+		t.Fatalf("expected a normal priority, got %q", got)
+	}
+}
+
+// TestPreflightValidation tests the validation of the recipients before the
+// first message sent to them
+func TestPreflightValidation(t *testing.T) {
+	var validations, messages int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/users/validate.json") {
+			atomic.AddInt32(&validations, 1)
+			if r.FormValue("user") != fakeRecipient.token {
+				fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user key is invalid"]}`)
+				return
+			}
+
+			fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+			return
+		}
+
+		atomic.AddInt32(&messages, 1)
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	p := New(fakePushover.token, WithPreflightValidation())
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+			t.Fatalf("expected no error, got %q", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&validations); got != 1 {
+		t.Fatalf("expected 1 validation, got %d", got)
+	}
+
+	invalid := NewRecipient("uQiRzpo4DXghDmr9QzzfQu27cmVRsG")
+	for i := 0; i < 2; i++ {
+		_, err := p.SendMessage(NewMessage("Hello"), invalid)
+		if !errors.Is(err, ErrInvalidRecipient) {
+			t.Fatalf("expected %q, got %v", ErrInvalidRecipient, err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&validations); got != 2 {
+		t.Fatalf("expected 2 validations, got %d", got)
+	}
+
+	if got := atomic.LoadInt32(&messages); got != 3 {
+		t.Fatalf("expected 3 messages, got %d", got)
+	}
+}
@@ -0,0 +1,144 @@
+package pushover
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OutboxEntry is a message waiting in an outbox to be sent again.
+type OutboxEntry struct {
+	Message *Message
+	UserKey string
+}
+
+// Outbox keeps the messages which couldn't be sent, e.g. while the API is
+// unreachable. A durable implementation, e.g. backed by a database, keeps
+// the messages across restarts.
+type Outbox interface {
+	// Enqueue adds the entry to the outbox.
+	Enqueue(ctx context.Context, entry OutboxEntry) error
+	// Dequeue removes the oldest entry from the outbox, ok is false if the
+	// outbox is empty.
+	Dequeue(ctx context.Context) (entry OutboxEntry, ok bool, err error)
+}
+
+// MemoryOutbox is an in-memory outbox, it's safe for concurrent use. The
+// messages are lost when the process exits.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	entries []OutboxEntry
+}
+
+// NewMemoryOutbox returns an empty in-memory outbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+// Enqueue adds the entry to the outbox.
+func (o *MemoryOutbox) Enqueue(ctx context.Context, entry OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.entries = append(o.entries, entry)
+	return nil
+}
+
+// Dequeue removes the oldest entry from the outbox.
+func (o *MemoryOutbox) Dequeue(ctx context.Context) (OutboxEntry, bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.entries) == 0 {
+		return OutboxEntry{}, false, nil
+	}
+
+	entry := o.entries[0]
+	o.entries = o.entries[1:]
+	return entry, true, nil
+}
+
+// Len returns the number of entries in the outbox.
+func (o *MemoryOutbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.entries)
+}
+
+// SendViaOutbox sends the message, it's added to the outbox if the send
+// fails with an error which can be retried later, e.g. a network error or
+// the quota exhaustion. The response then has its Queued flag set. The
+// messages with an attachment are never queued, the attachment can't be
+// kept. A copy of the message is queued, the message can be reused.
+func (p *Pushover) SendViaOutbox(ctx context.Context, outbox Outbox, message *Message, recipient *Recipient) (*Response, error) {
+	if recipient == nil {
+		recipient = p.defaultRecipient
+	}
+
+	response, err := p.SendMessageContext(ctx, message, recipient)
+	if err == nil || message.attachment != nil || !p.canQueue(err) {
+		return response, err
+	}
+
+	// Keep a copy, the message can be reused by the caller in the meantime
+	queued := *message
+	if err := outbox.Enqueue(ctx, OutboxEntry{Message: &queued, UserKey: recipient.token}); err != nil {
+		return nil, err
+	}
+
+	return &Response{Queued: true}, nil
+}
+
+// DrainOutbox sends the messages of the outbox every interval until the
+// context is done, it's meant to be run in the background. The messages
+// failing with an error which can be retried later are added back to the
+// outbox, the other ones are removed from the outbox and onDrop is called
+// with them and their error, e.g. to log them or keep them elsewhere.
+func (p *Pushover) DrainOutbox(ctx context.Context, outbox Outbox, interval time.Duration, onDrop func(OutboxEntry, error)) error {
+	if interval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.drainOutbox(ctx, outbox, onDrop); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// drainOutbox sends the messages of the outbox until it's empty or a send
+// fails with an error which can be retried later. The messages failing with
+// another error are reported to onDrop.
+func (p *Pushover) drainOutbox(ctx context.Context, outbox Outbox, onDrop func(OutboxEntry, error)) error {
+	for {
+		entry, ok, err := outbox.Dequeue(ctx)
+		if err != nil || !ok {
+			return err
+		}
+
+		_, err = p.SendMessageContext(ctx, entry.Message, NewRecipient(entry.UserKey))
+		if err != nil && p.canQueue(err) {
+			// Wait for the next attempt
+			return outbox.Enqueue(ctx, entry)
+		}
+
+		if err != nil && onDrop != nil {
+			onDrop(entry, err)
+		}
+	}
+}
+
+// canQueue returns true if the message can be sent later after the error.
+func (p *Pushover) canQueue(err error) bool {
+	return err == ErrQuotaExceeded || err == ErrClosed || p.isRetryable(err)
+}
@@ -0,0 +1,116 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestOutbox tests the messages queued while the API is down and drained
+// once it's back
+func TestOutbox(t *testing.T) {
+	var down int32 = 1
+	var mu sync.Mutex
+	var sent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.FormValue("user") != fakeRecipient.token {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"user":"invalid","errors":["user identifier is invalid"],"status":0,"request":"5042853c-402d-4a18-abcb-168734a801de"}`)
+			return
+		}
+
+		mu.Lock()
+		sent = append(sent, r.FormValue("message"))
+		mu.Unlock()
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	outbox := NewMemoryOutbox()
+	ctx := context.Background()
+
+	first := NewMessage("First")
+	response, err := fakePushover.SendViaOutbox(ctx, outbox, first, fakeRecipient)
+	if err != nil || !response.Queued {
+		t.Fatalf("expected a queued message, got %+v %v", response, err)
+	}
+
+	// The queued message is not affected by the later changes
+	first.Message = "Changed"
+
+	// The messages with an attachment are not queued
+	withAttachment := NewMessage("Second")
+	withAttachment.AddAttachmentBytes([]byte("image"), "image.png", "image/png")
+	if _, err := fakePushover.SendViaOutbox(ctx, outbox, withAttachment, fakeRecipient); err != ErrHTTPPushover {
+		t.Fatalf("expected %v, got %v", ErrHTTPPushover, err)
+	}
+
+	atomic.StoreInt32(&down, 0)
+
+	// The messages rejected by the API are not queued
+	if _, err := fakePushover.SendViaOutbox(ctx, outbox, NewMessage("Third"), fakeInvalidRecipient); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	response, err = fakePushover.SendViaOutbox(ctx, outbox, NewMessage("Fourth"), fakeRecipient)
+	if err != nil || response.Queued {
+		t.Fatalf("expected a sent message, got %+v %v", response, err)
+	}
+
+	if outbox.Len() != 1 {
+		t.Fatalf("expected 1 queued message, got %d", outbox.Len())
+	}
+
+	// The messages rejected by the API while draining are reported
+	rejected := OutboxEntry{Message: NewMessage("Fifth"), UserKey: fakeInvalidRecipient.token}
+	if err := outbox.Enqueue(ctx, rejected); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var dropped []OutboxEntry
+	onDrop := func(entry OutboxEntry, err error) {
+		if err == nil {
+			t.Errorf("expected an error for the dropped entry")
+		}
+		dropped = append(dropped, entry)
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := fakePushover.DrainOutbox(drainCtx, outbox, 10*time.Millisecond, onDrop); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if len(dropped) != 1 || dropped[0] != rejected {
+		t.Fatalf("expected the rejected entry to be dropped, got %+v", dropped)
+	}
+
+	if outbox.Len() != 0 {
+		t.Fatalf("expected an empty outbox, got %d messages", outbox.Len())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 || sent[0] != "Fourth" || sent[1] != "First" {
+		t.Fatalf("unexpected messages sent %v", sent)
+	}
+
+	if err := fakePushover.DrainOutbox(ctx, outbox, 0, onDrop); err != ErrInvalidPollInterval {
+		t.Fatalf("expected %v, got %v", ErrInvalidPollInterval, err)
+	}
+}
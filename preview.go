@@ -0,0 +1,99 @@
+package pushover
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+)
+
+// htmlTagRegexp matches the HTML tags.
+var htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// Preview represents a message as it's shown to the user.
+type Preview struct {
+	// Title is empty if the message has no title, the app name is shown
+	// instead.
+	Title string
+	// Body is the message, rendered as plain text for an HTML message.
+	Body string
+	// Sound is the sound played, none for the silent messages.
+	Sound string
+	// Priority is the name of the priority, e.g. high.
+	Priority string
+	// Attachment sums up the attachment, e.g. "image.png (image/png, 512
+	// bytes)", it's empty if the message has no attachment.
+	Attachment string
+}
+
+// Preview returns the message as it's shown to the user, e.g. for a preview
+// in a UI. The defaults of the app, e.g. WithDefaultTitle, are not applied.
+func (m *Message) Preview() Preview {
+	preview := Preview{
+		Title:    m.Title,
+		Body:     m.Message,
+		Sound:    m.Sound,
+		Priority: priorityName(m.Priority),
+	}
+
+	if m.Title == UseAppDefaultTitle {
+		preview.Title = ""
+	}
+
+	if m.HTML {
+		preview.Body = html.UnescapeString(htmlTagRegexp.ReplaceAllString(m.Message, ""))
+	}
+
+	switch {
+	case m.IsSilent():
+		preview.Sound = SoundNone
+	case m.Sound == "":
+		preview.Sound = SoundPushover
+	}
+
+	if m.attachment != nil {
+		preview.Attachment = m.attachmentSummary()
+	}
+
+	return preview
+}
+
+// priorityName returns the name of the priority, or its numeric form if
+// it's unknown.
+func priorityName(priority int) string {
+	if priority == UnsetPriority {
+		priority = PriorityNormal
+	}
+
+	for name, p := range priorityNames {
+		if p == priority {
+			return name
+		}
+	}
+
+	return fmt.Sprint(priority)
+}
+
+// attachmentSummary returns the name, the type and the size, if known, of
+// the attachment.
+func (m *Message) attachmentSummary() string {
+	name := m.attachmentName
+	if name == "" {
+		name = defaultAttachmentName
+	}
+
+	size := m.attachmentSize
+	if r, ok := m.attachment.(interface{ Len() int }); ok && size == 0 {
+		size = int64(r.Len())
+	}
+
+	switch {
+	case m.attachmentType != "" && size > 0:
+		return fmt.Sprintf("%s (%s, %d bytes)", name, m.attachmentType, size)
+	case m.attachmentType != "":
+		return fmt.Sprintf("%s (%s)", name, m.attachmentType)
+	case size > 0:
+		return fmt.Sprintf("%s (%d bytes)", name, size)
+	}
+
+	return name
+}
@@ -0,0 +1,71 @@
+package pushover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMessagePreview tests the messages as shown to the user
+func TestMessagePreview(t *testing.T) {
+	withAttachment := NewMessageWithTitle("Graph", "CPU")
+	withAttachment.AddAttachmentBytes([]byte("image"), "graph.png", "image/png")
+
+	withReader := NewMessage("Logs")
+	withReader.AddAttachment(strings.NewReader("logs"))
+
+	htmlMessage := NewHighPriorityMessage(`<b>Disk</b> full on <a href="https://example.com">web-1</a> &amp; web-2`)
+	htmlMessage.HTML = true
+	htmlMessage.Sound = SoundSiren
+
+	tt := []struct {
+		name     string
+		message  *Message
+		expected Preview
+	}{
+		{
+			name:     "default sound",
+			message:  NewMessage("Hello"),
+			expected: Preview{Body: "Hello", Sound: SoundPushover, Priority: "normal"},
+		},
+		{
+			name:     "app default title",
+			message:  NewMessageWithTitle("Hello", UseAppDefaultTitle),
+			expected: Preview{Body: "Hello", Sound: SoundPushover, Priority: "normal"},
+		},
+		{
+			name:     "html",
+			message:  htmlMessage,
+			expected: Preview{Body: "Disk full on web-1 & web-2", Sound: SoundSiren, Priority: "high"},
+		},
+		{
+			name:     "silent",
+			message:  NewLowPriorityMessage("Backup done"),
+			expected: Preview{Body: "Backup done", Sound: SoundNone, Priority: "low"},
+		},
+		{
+			name:     "emergency",
+			message:  NewEmergencyMessage("Server down", time.Minute, time.Hour),
+			expected: Preview{Body: "Server down", Sound: SoundPushover, Priority: "emergency"},
+		},
+		{
+			name:     "attachment",
+			message:  withAttachment,
+			expected: Preview{Title: "CPU", Body: "Graph", Sound: SoundPushover, Priority: "normal", Attachment: "graph.png (image/png, 5 bytes)"},
+		},
+		{
+			name:     "attachment reader",
+			message:  withReader,
+			expected: Preview{Body: "Logs", Sound: SoundPushover, Priority: "normal", Attachment: "attachment (4 bytes)"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.message.Preview(); !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package pushover
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Priorities by name.
+var priorityNames = map[string]int{
+	"lowest":    PriorityLowest,
+	"low":       PriorityLow,
+	"normal":    PriorityNormal,
+	"high":      PriorityHigh,
+	"emergency": PriorityEmergency,
+}
+
+// ParsePriority returns the priority from its case insensitive name (lowest,
+// low, normal, high or emergency) or its numeric form. It returns an
+// ErrInvalidPriority if the priority is unknown.
+func ParsePriority(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if priority, ok := priorityNames[s]; ok {
+		return priority, nil
+	}
+
+	priority, err := strconv.Atoi(s)
+	if err != nil || priority < PriorityLowest || priority > PriorityEmergency {
+		return 0, ErrInvalidPriority
+	}
+
+	return priority, nil
+}
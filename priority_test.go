@@ -0,0 +1,37 @@
+package pushover
+
+import "testing"
+
+// TestParsePriority tests the priority parsing
+func TestParsePriority(t *testing.T) {
+	tt := []struct {
+		name     string
+		input    string
+		expected int
+		err      error
+	}{
+		{"lowest", "lowest", PriorityLowest, nil},
+		{"low", "low", PriorityLow, nil},
+		{"normal", "normal", PriorityNormal, nil},
+		{"high", "High", PriorityHigh, nil},
+		{"emergency", " EMERGENCY ", PriorityEmergency, nil},
+		{"numeric lowest", "-2", PriorityLowest, nil},
+		{"numeric emergency", "2", PriorityEmergency, nil},
+		{"numeric out of range", "3", 0, ErrInvalidPriority},
+		{"unknown name", "urgent", 0, ErrInvalidPriority},
+		{"empty", "", 0, ErrInvalidPriority},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParsePriority(tc.input)
+			if err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if got != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
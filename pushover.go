@@ -2,11 +2,15 @@
 package pushover
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Regexp validation.
@@ -40,8 +44,43 @@ var (
 	ErrMissingEmergencyParameter  = errors.New("pushover: missing emergency parameter")
 	ErrInvalidDeviceName          = errors.New("pushover: invalid device name")
 	ErrEmptyReceipt               = errors.New("pushover: empty receipt")
+	ErrInvalidTTL                 = errors.New("pushover: invalid TTL, it should be at least a second")
+	ErrTTLWithEmergency           = errors.New("pushover: TTL can't be used with an emergency priority")
+	ErrInvalidControlChars        = errors.New("pushover: invalid control characters in the message or title")
+	ErrClosed                     = errors.New("pushover: app closed")
+	ErrGlanceEmpty                = errors.New("pushover: glance without any field to update")
+	ErrGlanceFieldTooLong         = errors.New("pushover: glance field too long")
+	ErrInvalidGlancePercent       = errors.New("pushover: invalid glance percent, it should be between 0 and 100")
+	ErrInvalidPollInterval        = errors.New("pushover: invalid poll interval, it should be positive")
+	ErrUnknownDevice              = errors.New("pushover: unknown device for the recipient")
+	ErrAttachmentSizeMismatch     = errors.New("pushover: attachment size doesn't match its read length")
+	ErrMissingSubscriptionKey     = errors.New("pushover: missing subscribed user key")
+	ErrCantResend                 = errors.New("pushover: response without a message that can be sent again")
+	ErrReceiptExpired             = errors.New("pushover: notification expired without acknowledgement")
+	ErrNotEmergency               = errors.New("pushover: message priority is not emergency")
+	ErrQuotaExceeded              = errors.New("pushover: monthly message quota exceeded")
+	ErrUnknownSound               = errors.New("pushover: unknown sound")
+	ErrInvalidURL                 = errors.New("pushover: invalid URL in the HTML message, only http and https are allowed")
+	ErrReceiptNotFound            = errors.New("pushover: receipt not found, it may be invalid or expired")
+	ErrInvalidTemplate            = errors.New("pushover: invalid template")
+	ErrInvalidAttachmentRange     = errors.New("pushover: invalid attachment range, the offset and length should be positive")
+	ErrMissingAckFunc             = errors.New("pushover: missing acknowledgement func")
+
+	// ErrMalformedToken is returned before any request for a token which is
+	// not 30 alphanumeric characters.
+	ErrMalformedToken = ErrInvalidToken
+	// ErrMalformedUserKey is returned before any request for a user key
+	// which is not 30 alphanumeric characters.
+	ErrMalformedUserKey = ErrInvalidRecipientToken
 )
 
+// newlineReplacer replaces the Windows and old Mac line endings.
+var newlineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// ansiRegexp matches the ANSI escape sequences, e.g. the colors of a
+// terminal output.
+var ansiRegexp = regexp.MustCompile(`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
 // API limitations.
 const (
 	// MessageMaxLength is the max message number of characters.
@@ -54,6 +93,9 @@ const (
 	MessageURLTitleMaxLength = 100
 	// MessageMaxAttachementByte is the max attachement size in byte.
 	MessageMaxAttachementByte = 2621440
+	// GlanceFieldMaxLength is the max glance title, text and subtext number
+	// of characters.
+	GlanceFieldMaxLength = 100
 )
 
 // Message priorities
@@ -63,6 +105,11 @@ const (
 	PriorityNormal    = 0
 	PriorityHigh      = 1
 	PriorityEmergency = 2
+
+	// UnsetPriority is the priority of the messages created with NewMessage
+	// and NewMessageWithTitle, they're sent with the default priority of the
+	// app, normal unless set with WithDefaultPriority.
+	UnsetPriority = 100
 )
 
 // Sounds
@@ -88,17 +135,302 @@ const (
 	SoundPersistent   = "persistent"
 	SoundEcho         = "echo"
 	SoundUpDown       = "updown"
+	SoundVibrate      = "vibrate"
 	SoundNone         = "none"
 )
 
 // Pushover is the representation of an app using the pushover API.
 type Pushover struct {
-	token string
+	token   string
+	appName string
+
+	// Options
+	ctx            context.Context
+	strictDecoding bool
+	jsonTransport  bool
+
+	// Requests
+	client            *http.Client
+	transport         *http.Transport
+	multipartBoundary string
+	maxRetries        int
+	retryBackoff      time.Duration
+	backoffJitter     float64
+	maxBackoff        time.Duration
+	retryPredicate    func(error) bool
+	timeout           time.Duration
+	batchRetryBudget  int
+	maxConcurrency    int
+
+	// Metrics
+	metrics           MetricsCollector
+	stats             counters
+	slowSendThreshold time.Duration
+	onSlowSend        func(time.Duration, *Message)
+
+	// Debugging
+	captureLastRequest bool
+	lastRequest        []byte
+	captureRawResponse bool
+
+	// Validation
+	validators       []func(*Message) error
+	strictValidation bool
+	skipValidation   bool
+
+	// Dedupe
+	dedupeWindow time.Duration
+	dedupeStore  DedupeStore
+
+	// Preflight
+	preflightValidation bool
+	preflightResults    map[string]error
+
+	// Fallbacks
+	attachmentFallback bool
+	soundFallback      []string
+	fallbackRecipient  *Recipient
+	devices            map[string][]string
+	customSounds       map[string]string
+	messageLimit       int
+	titleLimit         int
+
+	// Clock
+	clock func() time.Time
+
+	// Defaults
+	defaultRecipient   *Recipient
+	autoTimestamp      bool
+	normalizeNewlines  bool
+	emojiShortcodes    bool
+	stripANSI          bool
+	defaultDevice      string
+	titlePrefix        string
+	defaultTitle       string
+	titleFromFirstLine bool
+	emergencyRetry     time.Duration
+	emergencyExpire    time.Duration
+	defaultPriority    int
+	quietHours         *quietHours
+
+	// Quota
+	limit              *Limit
+	optimisticQuota    bool
+	quotaBreaker       bool
+	downgradeThreshold int
+
+	// Lifecycle
+	mu            sync.Mutex
+	closed        bool
+	wg            sync.WaitGroup
+	inFlight      map[int]context.CancelFunc
+	nextInFlight  int
+	scheduled     map[int]context.CancelFunc
+	nextScheduled int
 }
 
 // New returns a new app to talk to the pushover API.
-func New(token string) *Pushover {
-	return &Pushover{token}
+func New(token string, opts ...Option) *Pushover {
+	p := &Pushover{token: token}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.client = http.DefaultClient
+	if p.transport != nil {
+		p.client = &http.Client{Transport: p.transport}
+	}
+
+	return p
+}
+
+// Close waits for the pending requests to complete and releases the
+// resources used by the app, e.g. the idle connections of the transport set
+// up by WithInsecureTLS or WithForceHTTP2. The scheduled sends not started yet are
+// stopped. The app is unusable after Close, any request returns an
+// ErrClosed.
+func (p *Pushover) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	for id, cancel := range p.scheduled {
+		cancel()
+		delete(p.scheduled, id)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	// Close the keep-alive connections of the app transport, the default
+	// one is shared with the rest of the program
+	if p.transport != nil {
+		p.transport.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+// acquire registers a pending work, it returns false if the app is closed.
+// The work must call release when done.
+func (p *Pushover) acquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return false
+	}
+
+	p.wg.Add(1)
+	return true
+}
+
+// release unregisters a pending work.
+func (p *Pushover) release() {
+	p.wg.Done()
+}
+
+// CancelAll cancels all the requests in progress, the remaining sends of the
+// bulk sends and the pending scheduled sends, e.g. as a kill switch during a
+// broadcast storm. The cancelled sends return a context.Canceled, the app can
+// still be used afterwards.
+func (p *Pushover) CancelAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, cancel := range p.inFlight {
+		cancel()
+		delete(p.inFlight, id)
+	}
+}
+
+// track registers the cancel func of a request for CancelAll, it returns the
+// func to unregister it when the request is done.
+func (p *Pushover) track(cancel context.CancelFunc) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.inFlight == nil {
+		p.inFlight = map[int]context.CancelFunc{}
+	}
+
+	id := p.nextInFlight
+	p.nextInFlight++
+	p.inFlight[id] = cancel
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		delete(p.inFlight, id)
+	}
+}
+
+// schedule registers the cancel func of a scheduled send for Close, it
+// returns the func to unregister it when the send is done.
+func (p *Pushover) schedule(cancel context.CancelFunc) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.scheduled == nil {
+		p.scheduled = map[int]context.CancelFunc{}
+	}
+
+	id := p.nextScheduled
+	p.nextScheduled++
+	p.scheduled[id] = cancel
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		delete(p.scheduled, id)
+	}
+}
+
+// LastRequestDump returns the raw bytes of the last request sent to the API
+// with the tokens redacted. The requests are only captured with the
+// WithCaptureLastRequest option, it returns nil otherwise.
+func (p *Pushover) LastRequestDump() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lastRequest == nil {
+		return nil
+	}
+
+	dump := make([]byte, len(p.lastRequest))
+	copy(dump, p.lastRequest)
+	return dump
+}
+
+// now returns the current time from the app clock.
+func (p *Pushover) now() time.Time {
+	if p.clock == nil {
+		return time.Now()
+	}
+
+	return p.clock()
+}
+
+// maxLength returns the max message number of characters of the app.
+func (p *Pushover) maxLength() int {
+	if p.messageLimit > 0 {
+		return p.messageLimit
+	}
+
+	return MessageMaxLength
+}
+
+// maxTitleLength returns the max title number of characters of the app.
+func (p *Pushover) maxTitleLength() int {
+	if p.titleLimit > 0 {
+		return p.titleLimit
+	}
+
+	return MessageTitleMaxLength
+}
+
+// prefixTitle returns the title with the prefix, the title is truncated to
+// keep the result within max characters. The prefix trailing spaces are
+// removed from an empty title.
+func prefixTitle(prefix, title string, max int) string {
+	if title == "" {
+		title = strings.TrimRight(prefix, " ")
+	} else {
+		title = prefix + title
+	}
+
+	if runes := []rune(title); len(runes) > max {
+		title = string(runes[:max])
+	}
+
+	return title
+}
+
+// firstLine returns the first line of the message, without its surrounding
+// spaces, truncated to max characters.
+func firstLine(message string, max int) string {
+	if i := strings.IndexAny(message, "\r\n"); i >= 0 {
+		message = message[:i]
+	}
+
+	line := strings.TrimSpace(message)
+	if runes := []rune(line); len(runes) > max {
+		line = string(runes[:max])
+	}
+
+	return line
+}
+
+// AppName returns the name of the app set with WithAppName, the API doesn't
+// expose the registered name.
+func (p *Pushover) AppName() string {
+	return p.appName
+}
+
+// ValidToken reports whether s is formatted as an app token, it doesn't check
+// that the token exists.
+func ValidToken(s string) bool {
+	return tokenRegexp.MatchString(s)
 }
 
 // Validate Pushover token.
@@ -109,60 +441,395 @@ func (p *Pushover) validate() error {
 	}
 
 	// Check invalid token
-	if tokenRegexp.MatchString(p.token) == false {
+	if !ValidToken(p.token) {
 		return ErrInvalidToken
 	}
 	return nil
 }
 
-// SendMessage is used to send message to a recipient.
+// Notifier sends messages to the recipients, it's implemented by Pushover so
+// that the application code can depend on it and use a fake in the tests.
+type Notifier interface {
+	SendMessageContext(ctx context.Context, message *Message, recipient *Recipient) (*Response, error)
+}
+
+var _ Notifier = (*Pushover)(nil)
+
+// SendMessage is used to send message to a recipient, or to the app default
+// recipient if nil. When the API rejects
+// the message, the response is returned along with the Errors so that its
+// request ID can be logged.
 func (p *Pushover) SendMessage(message *Message, recipient *Recipient) (*Response, error) {
+	return p.SendMessageContext(context.Background(), message, recipient)
+}
+
+// SendMessageContext is used to send message to a recipient, the request is
+// aborted when the context is done.
+func (p *Pushover) SendMessageContext(ctx context.Context, message *Message, recipient *Recipient) (*Response, error) {
 	// Validate pushover
 	if err := p.validate(); err != nil {
 		return nil, err
 	}
 
+	// Send to the default recipient if none is given
+	if recipient == nil {
+		recipient = p.defaultRecipient
+		if recipient == nil {
+			return nil, ErrEmptyRecipientToken
+		}
+	}
+
 	// Validate recipient
 	if err := recipient.validate(); err != nil {
 		return nil, err
 	}
 
-	// Validate message
-	if err := message.validate(); err != nil {
+	// Validate the recipient with the API before the first send
+	if err := p.preflight(ctx, recipient); err != nil {
+		return nil, err
+	}
+
+	// Keep the message as given to send it again with Resend, the app
+	// defaults are applied again then
+	original := *message
+
+	// Apply the app defaults
+	message = p.prepareMessage(message)
+
+	// Stretch the remaining quota
+	downgraded := p.downgrade(message)
+
+	// Validate message, unless deferred to the API
+	if !p.skipValidation {
+		if err := message.validateWithLimits(p.maxLength(), p.maxTitleLength()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate message with the custom validators
+	for _, validator := range p.validators {
+		if err := validator(message); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the device against the cached recipient devices
+	if err := p.validateDevice(message, recipient); err != nil {
+		return nil, err
+	}
+
+	// Validate the sound against the built-in and fetched sounds
+	if err := p.validateSound(message); err != nil {
 		return nil, err
 	}
 
-	return message.send(p.token, recipient.token)
+	// Don't send the doomed messages while the quota is exhausted
+	if p.quotaBreakerOpen(message) {
+		return nil, ErrQuotaExceeded
+	}
+
+	// Swallow the duplicates of the messages recently sent
+	key, duplicate, err := p.dedupe(ctx, message, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	if duplicate {
+		return &Response{Deduped: true}, nil
+	}
+
+	// Keep the attachment to send it again to the fallback recipient
+	var attachment []byte
+	if p.fallbackRecipient != nil && message.attachment != nil {
+		data, err := message.readAttachment()
+		if err != nil {
+			return nil, err
+		}
+		attachment = data
+		message.attachment = bytes.NewReader(data)
+	}
+
+	start := p.now()
+	response, err := message.send(ctx, p, recipient.token)
+
+	// Deliver the text when the gateway can't relay the attachment
+	if err != nil && p.attachmentFallback && message.attachment != nil && isAttachmentUnsupported(err) {
+		withoutAttachment := *message
+		withoutAttachment.setAttachment(nil, "", "")
+		response, err = withoutAttachment.send(ctx, p, recipient.token)
+		if response != nil {
+			response.AttachmentDropped = true
+		}
+	}
+
+	// Reach the fallback recipient when the API rejects the recipient, the
+	// rejected messages would be rejected for the fallback recipient as well
+	if isRecipientRejected(response, err) && p.canFallback(recipient) {
+		recipient = p.fallbackRecipient
+		toFallback := *message
+		if attachment != nil {
+			toFallback.attachment = bytes.NewReader(attachment)
+		}
+
+		response, err = toFallback.send(ctx, p, recipient.token)
+		if response != nil {
+			response.FallbackUsed = true
+		}
+	}
+
+	// The message can be sent again within the dedupe window
+	if err != nil && key != "" {
+		p.dedupeStore.Remove(ctx, key)
+	}
+
+	if response != nil {
+		response.Downgraded = downgraded
+		response.message = &original
+		response.recipient = recipient
+	}
+
+	p.stats.observe(err)
+	p.observeSend(ctx, message, start, err)
+
+	return response, err
+}
+
+// canFallback returns true if a message rejected for the recipient can be
+// sent to the fallback recipient.
+func (p *Pushover) canFallback(recipient *Recipient) bool {
+	return p.fallbackRecipient != nil &&
+		p.fallbackRecipient.token != recipient.token &&
+		p.fallbackRecipient.validate() == nil
+}
+
+// SendToSubscriber is used to send a message to a user subscribed to the app
+// through a subscription. The subscribed user keys are specific to the app,
+// they share the format of the user keys and are validated the same way.
+func (p *Pushover) SendToSubscriber(message *Message, subscriber *Recipient) (*Response, error) {
+	return p.SendMessage(message, subscriber)
+}
+
+// Resend sends again the message of a previous response, e.g. after a
+// failure reported by the API. The messages with an attachment can't be sent
+// again, their attachment has been consumed.
+func (p *Pushover) Resend(ctx context.Context, response *Response) (*Response, error) {
+	if response == nil || response.message == nil {
+		return nil, ErrCantResend
+	}
+
+	if response.message.attachment != nil {
+		return nil, ErrCantResend
+	}
+
+	return p.SendMessageContext(ctx, response.message, response.recipient)
+}
+
+// SendEmergency sends an emergency message and fetches the details of its
+// receipt right away, to record its initial state. It returns an
+// ErrNotEmergency for the other priorities, the default priority of the app
+// included.
+func (p *Pushover) SendEmergency(ctx context.Context, message *Message, recipient *Recipient) (*ReceiptDetails, error) {
+	if p.priority(message) != PriorityEmergency {
+		return nil, ErrNotEmergency
+	}
+
+	response, err := p.SendMessageContext(ctx, message, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.getReceiptDetails(ctx, response.Receipt)
+}
+
+// testNotification is the message sent by SendTest.
+const testNotification = "This is a test notification, your notifications are set up correctly."
+
+// SendTest sends a standard low priority test notification to the
+// recipient, e.g. to check the setup of a user key.
+func (p *Pushover) SendTest(ctx context.Context, recipient *Recipient) (*Response, error) {
+	return p.SendMessageContext(ctx, NewLowPriorityMessage(testNotification), recipient)
+}
+
+// SendIf sends the message only if the condition returns true, e.g. outside
+// of a maintenance window. Otherwise the message isn't sent and the response
+// has its Skipped flag set.
+func (p *Pushover) SendIf(ctx context.Context, message *Message, recipient *Recipient, cond func() bool) (*Response, error) {
+	if cond != nil && !cond() {
+		return &Response{Skipped: true}, nil
+	}
+
+	return p.SendMessageContext(ctx, message, recipient)
+}
+
+// priority returns the priority the message is sent with, the default
+// priority of the app for the UnsetPriority.
+func (p *Pushover) priority(message *Message) int {
+	if message.Priority == UnsetPriority {
+		return p.defaultPriority
+	}
+
+	return message.Priority
+}
+
+// prepareMessage returns a copy of the message with the app defaults
+// applied, the original message is left untouched.
+func (p *Pushover) prepareMessage(message *Message) *Message {
+	m := *message
+	m.Priority = p.priority(message)
+
+	if p.normalizeNewlines {
+		m.Message = newlineReplacer.Replace(m.Message)
+	}
+
+	if p.stripANSI {
+		m.Message = ansiRegexp.ReplaceAllString(m.Message, "")
+	}
+
+	if p.emojiShortcodes {
+		m.Message = emojiReplacer.Replace(m.Message)
+	}
+
+	if m.Title == UseAppDefaultTitle {
+		m.Title = ""
+	} else {
+		if m.Title == "" && p.titleFromFirstLine {
+			m.Title = firstLine(m.Message, p.maxTitleLength())
+		}
+
+		if m.Title == "" {
+			m.Title = p.defaultTitle
+		}
+
+		if p.emojiShortcodes {
+			m.Title = emojiReplacer.Replace(m.Title)
+		}
+
+		if p.titlePrefix != "" {
+			m.Title = prefixTitle(p.titlePrefix, m.Title, p.maxTitleLength())
+		}
+	}
+
+	m.Sound = p.fallbackSound(m.Sound)
+
+	if m.DeviceName == "" && len(m.Devices) == 0 {
+		m.DeviceName = p.defaultDevice
+	}
+
+	if m.Timestamp == 0 && p.autoTimestamp {
+		m.Timestamp = p.now().Unix()
+	}
+
+	p.quiet(&m)
+
+	if m.Priority == PriorityEmergency {
+		if m.Retry == 0 {
+			m.Retry = p.emergencyRetry
+		}
+
+		if m.Expire == 0 {
+			m.Expire = p.emergencyExpire
+		}
+	}
+
+	return &m
 }
 
 // GetReceiptDetails return detailed informations about a receipt. This is used
 // used to check the acknowledged status of an Emergency notification.
 func (p *Pushover) GetReceiptDetails(receipt string) (*ReceiptDetails, error) {
+	return p.getReceiptDetails(context.Background(), receipt)
+}
+
+func (p *Pushover) getReceiptDetails(ctx context.Context, receipt string) (*ReceiptDetails, error) {
 	url := fmt.Sprintf("%s/receipts/%s.json?token=%s", APIEndpoint, receipt, p.token)
 
 	if receipt == "" {
 		return nil, ErrEmptyReceipt
 	}
 
-	// Send request
-	resp, err := http.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, p.redactError(err)
 	}
+	req = req.WithContext(ctx)
 
-	// Decode the JSON response
-	var details *ReceiptDetails
-	if err = json.NewDecoder(resp.Body).Decode(&details); err != nil {
+	details := &ReceiptDetails{}
+	if err := p.do(req, details, false); err != nil {
 		return nil, err
 	}
 
 	return details, nil
 }
 
+// PollReceiptFunc gets the receipt details every interval and calls fn with
+// them until fn returns true, an error occurs or the context is done.
+func (p *Pushover) PollReceiptFunc(ctx context.Context, receipt string, interval time.Duration, fn func(*ReceiptDetails) bool) error {
+	if interval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		details, err := p.getReceiptDetails(ctx, receipt)
+		if err != nil {
+			return err
+		}
+
+		if fn(details) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WatchReceipt polls the receipt every interval in the background, sending
+// each details on the first channel until the notification is acknowledged
+// or expired. A poll error or the context end is sent on the second channel
+// and stops the polling. Both channels are closed when the polling stops.
+func (p *Pushover) WatchReceipt(ctx context.Context, receipt string, interval time.Duration) (<-chan *ReceiptDetails, <-chan error) {
+	details := make(chan *ReceiptDetails)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(details)
+
+		err := p.PollReceiptFunc(ctx, receipt, interval, func(d *ReceiptDetails) bool {
+			select {
+			case details <- d:
+			case <-ctx.Done():
+				return true
+			}
+
+			return d.Acknowledged || d.Expired
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return details, errs
+}
+
 // GetRecipientDetails allows to check if a recipient exists, if it's a group
 // and the devices associated to this recipient. It returns an
 // ErrInvalidRecipient if the recipient is not valid in the Pushover API.
 func (p *Pushover) GetRecipientDetails(recipient *Recipient) (*RecipientDetails, error) {
+	return p.getRecipientDetails(context.Background(), recipient)
+}
+
+func (p *Pushover) getRecipientDetails(ctx context.Context, recipient *Recipient) (*RecipientDetails, error) {
 	endpoint := fmt.Sprintf("%s/users/validate.json", APIEndpoint)
 
 	// Validate pushover
@@ -180,12 +847,17 @@ func (p *Pushover) GetRecipientDetails(recipient *Recipient) (*RecipientDetails,
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
 
 	var response RecipientDetails
-	if err := do(req, &response, false); err != nil {
+	if err := p.do(req, &response, false, recipient.token); err != nil {
 		return nil, err
 	}
 
+	if p.strictValidation {
+		p.cacheDevices(recipient, response.Devices)
+	}
+
 	return &response, nil
 }
 
@@ -201,8 +873,8 @@ func (p *Pushover) CancelEmergencyNotification(receipt string) (*Response, error
 	}
 
 	response := &Response{}
-	if err := do(req, response, false); err != nil {
-		return nil, err
+	if err := p.do(req, response, false); err != nil {
+		return responseOnError(response, err)
 	}
 
 	return response, nil
@@ -1,10 +1,15 @@
 package pushover
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -97,15 +102,16 @@ func TestValidPostForm(t *testing.T) {
 	}
 
 	got := &Response{}
-	if err := do(req, got, true); err != nil {
+	if err := fakePushover.do(req, got, true); err != nil {
 		t.Fatalf("failed to do request: %v", err)
 	}
 
 	expected := &Response{
-		Status:  1,
-		ID:      "e460545a8b333d0da2f3602aff3133d6",
-		Errors:  nil,
-		Receipt: "",
+		Status:     1,
+		ID:         "e460545a8b333d0da2f3602aff3133d6",
+		Errors:     nil,
+		Receipt:    "",
+		HTTPStatus: http.StatusOK,
 		Limit: &Limit{
 			Total:     7500,
 			Remaining: 6000,
@@ -131,7 +137,7 @@ func TestPostFormErrors(t *testing.T) {
 	}
 
 	got := &Response{}
-	err = do(req, got, true)
+	err = fakePushover.do(req, got, true)
 	if err == nil {
 		t.Fatalf("expected an error, got nil")
 	}
@@ -257,11 +263,12 @@ func TestCancelEmergencyNotification(t *testing.T) {
 	}
 
 	expected := &Response{
-		Status:  1,
-		ID:      "e460545a8b333d0da2f3602aff3133d6",
-		Errors:  nil,
-		Receipt: "",
-		Limit:   nil,
+		Status:     1,
+		ID:         "e460545a8b333d0da2f3602aff3133d6",
+		Errors:     nil,
+		Receipt:    "",
+		HTTPStatus: http.StatusOK,
+		Limit:      nil,
 	}
 
 	if reflect.DeepEqual(got, expected) == false {
@@ -286,18 +293,395 @@ func TestSendMessage(t *testing.T) {
 	}
 
 	expected := &Response{
-		Status:  1,
-		ID:      "e460545a8b333d0da2f3602aff3133d6",
-		Errors:  nil,
-		Receipt: "",
+		Status:     1,
+		ID:         "e460545a8b333d0da2f3602aff3133d6",
+		Errors:     nil,
+		Receipt:    "",
+		HTTPStatus: http.StatusOK,
 		Limit: &Limit{
 			Total:     7500,
 			Remaining: 6000,
 			NextReset: time.Unix(int64(1393653600), 0),
 		},
+		message:   NewMessage("TestMessage"),
+		recipient: fakeRecipient,
 	}
 
 	if reflect.DeepEqual(got, expected) == false {
 		t.Errorf("unexpected response from postFrom")
 	}
 }
+
+// TestClose tests that the pending requests are completed and the app is
+// unusable once closed
+func TestClose(t *testing.T) {
+	var handled int32
+	requestStarted := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requestStarted)
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&handled, 1)
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	p := New(fakePushover.token)
+
+	go p.CancelEmergencyNotification("receipt")
+
+	<-requestStarted
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The pending request should be done when Close returns
+	if atomic.LoadInt32(&handled) != 1 {
+		t.Fatalf("the pending request should be completed")
+	}
+
+	if _, err := p.CancelEmergencyNotification("receipt"); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
+
+// TestCloseIdleConnections tests that the keep-alive connections of the app
+// transport are closed by Close
+func TestCloseIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	p := New(fakePushover.token, WithInsecureTLS())
+	if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the idle connection to be closed")
+	}
+}
+
+// TestCancelAll tests that the requests in progress are cancelled and the
+// app is still usable afterwards
+func TestCancelAll(t *testing.T) {
+	started := make(chan struct{}, 3)
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("message") == "Blocked" {
+			started <- struct{}{}
+			select {
+			case <-block:
+			case <-r.Context().Done():
+			}
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	defer close(block)
+	APIEndpoint = ts.URL
+
+	p := New(fakePushover.token)
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := p.SendMessage(NewMessage("Blocked"), fakeRecipient)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	p.CancelAll()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected %v, got %v", context.Canceled, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the sends to be cancelled")
+		}
+	}
+
+	if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+// TestResponseInfo tests the advisory messages of a successful response
+func TestResponseInfo(t *testing.T) {
+	tt := []struct {
+		name     string
+		body     string
+		expected Info
+	}{
+		{"no info", `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`, nil},
+		{"info string", `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","info":"no active devices"}`, Info{"no active devices"}},
+		{"info list", `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","info":["info1","info2"]}`, Info{"info1", "info2"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, tc.body)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			got, err := fakePushover.CancelEmergencyNotification("receipt")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Info, tc.expected) {
+				t.Fatalf("expected info %v, got %v", tc.expected, got.Info)
+			}
+
+			if got.HTTPStatus != http.StatusOK {
+				t.Fatalf("expected HTTP status %d, got %d", http.StatusOK, got.HTTPStatus)
+			}
+		})
+	}
+}
+
+// TestSendToSubscriber tests the message sent to a subscribed user
+func TestSendToSubscriber(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	subscriber := NewRecipient("uQiRzpo4DXghDmr9QzzfQu27cmVRsG")
+	if _, err := fakePushover.SendToSubscriber(NewMessage("Hello"), subscriber); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	invalid := NewRecipient("invalid-subscriber")
+	if _, err := fakePushover.SendToSubscriber(NewMessage("Hello"), invalid); err != ErrInvalidRecipientToken {
+		t.Fatalf("expected %v, got %v", ErrInvalidRecipientToken, err)
+	}
+}
+
+// TestSendTest tests the test notification
+func TestSendTest(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	response, err := fakePushover.SendTest(context.Background(), fakeRecipient)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if response.message.Message != testNotification {
+		t.Fatalf("expected the test notification, got %q", response.message.Message)
+	}
+
+	if response.message.Priority != PriorityLow {
+		t.Fatalf("expected a low priority, got %d", response.message.Priority)
+	}
+
+	if _, err := fakePushover.SendTest(context.Background(), NewRecipient("invalid")); err != ErrInvalidRecipientToken {
+		t.Fatalf("expected %v, got %v", ErrInvalidRecipientToken, err)
+	}
+}
+
+// TestSendIf tests the messages sent depending on a condition
+func TestSendIf(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	tt := []struct {
+		name             string
+		cond             func() bool
+		expectedSkipped  bool
+		expectedRequests int32
+	}{
+		{"true", func() bool { return true }, false, 1},
+		{"false", func() bool { return false }, true, 0},
+		{"nil", nil, false, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			response, err := fakePushover.SendIf(context.Background(), NewMessage("Hello"), fakeRecipient, tc.cond)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if response.Skipped != tc.expectedSkipped {
+				t.Fatalf("expected skipped %t, got %t", tc.expectedSkipped, response.Skipped)
+			}
+
+			if got := atomic.LoadInt32(&requests); got != tc.expectedRequests {
+				t.Fatalf("expected %d requests, got %d", tc.expectedRequests, got)
+			}
+		})
+	}
+}
+
+// TestPartialResponse tests that the response of a logical failure is
+// returned along with the errors
+func TestPartialResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user %s is invalid"]}`, fakeRecipient.token)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	response, err := fakePushover.SendMessage(NewMessage("Hello"), fakeRecipient)
+	if _, ok := err.(Errors); !ok {
+		t.Fatalf("expected Errors, got %#v", err)
+	}
+
+	if response == nil {
+		t.Fatalf("expected a response, got nil")
+	}
+
+	if response.ID != "e460545a8b333d0da2f3602aff3133d6" {
+		t.Fatalf("expected the request ID, got %q", response.ID)
+	}
+
+	expected := Errors{"user <redacted> is invalid"}
+	if !reflect.DeepEqual(response.Errors, expected) {
+		t.Fatalf("expected %v, got %v", expected, response.Errors)
+	}
+}
+
+// TestResend tests the message of a response sent again
+func TestResend(t *testing.T) {
+	var messages []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		messages = append(messages, r.FormValue("message"))
+		fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	response, _ := fakePushover.SendMessage(NewMessage("Hello"), fakeRecipient)
+	if _, err := fakePushover.Resend(context.Background(), response); err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	if !reflect.DeepEqual(messages, []string{"Hello", "Hello"}) {
+		t.Fatalf("expected the message to be sent twice, got %v", messages)
+	}
+
+	// The app defaults are applied once to the message sent again
+	var titles []string
+	tsTitles := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		titles = append(titles, r.FormValue("title"))
+		fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+	}))
+	defer tsTitles.Close()
+	APIEndpoint = tsTitles.URL
+
+	p := New(fakePushover.token, WithTitlePrefix("[h] "))
+	response, _ = p.SendMessage(NewMessageWithTitle("Hello", "t"), fakeRecipient)
+	if _, err := p.Resend(context.Background(), response); err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	if !reflect.DeepEqual(titles, []string{"[h] t", "[h] t"}) {
+		t.Fatalf("expected the prefixed title to be sent twice, got %v", titles)
+	}
+
+	APIEndpoint = ts.URL
+	withAttachment := NewMessage("Hello")
+	withAttachment.AddAttachment(strings.NewReader("fake image"))
+	response, _ = fakePushover.SendMessage(withAttachment, fakeRecipient)
+
+	tt := []struct {
+		name     string
+		response *Response
+	}{
+		{"nil response", nil},
+		{"decoded response", &Response{Status: 1}},
+		{"attachment", response},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := fakePushover.Resend(context.Background(), tc.response); err != ErrCantResend {
+				t.Fatalf("expected %v, got %v", ErrCantResend, err)
+			}
+		})
+	}
+}
+
+// TestFieldErrors tests the field errors of the error responses
+func TestFieldErrors(t *testing.T) {
+	tt := []struct {
+		name     string
+		body     string
+		expected map[string][]string
+	}{
+		{
+			name:     "field error string",
+			body:     `{"user":"invalid","errors":["user identifier is invalid"],"status":0,"request":"5042853c-402d-4a18-abcb-168734a801de"}`,
+			expected: map[string][]string{"user": {"invalid"}},
+		},
+		{
+			name:     "field error list",
+			body:     `{"message":["is empty"],"title":["is too long"],"errors":["message cannot be blank"],"status":0,"request":"5042853c-402d-4a18-abcb-168734a801de"}`,
+			expected: map[string][]string{"message": {"is empty"}, "title": {"is too long"}},
+		},
+		{
+			name: "no field error",
+			body: `{"errors":["application token is invalid"],"status":0,"request":"5042853c-402d-4a18-abcb-168734a801de"}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintln(w, tc.body)
+			}))
+			defer ts.Close()
+			APIEndpoint = ts.URL
+
+			response, err := fakePushover.SendMessage(NewMessage("Hello"), fakeRecipient)
+			if _, ok := err.(Errors); !ok {
+				t.Fatalf("expected the API errors, got %v", err)
+			}
+
+			if !reflect.DeepEqual(response.FieldErrors, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, response.FieldErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package pushover
+
+import "time"
+
+// quietHours is a daily period in a time zone, it spans midnight if it ends
+// before it starts.
+type quietHours struct {
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+// contains returns true if the time is within the quiet hours.
+func (q *quietHours) contains(t time.Time) bool {
+	t = t.In(q.location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+
+	if q.start <= q.end {
+		return sinceMidnight >= q.start && sinceMidnight < q.end
+	}
+
+	return sinceMidnight >= q.start || sinceMidnight < q.end
+}
+
+// quiet lowers the priority of the message during the quiet hours, the
+// emergency messages always go through.
+func (p *Pushover) quiet(m *Message) {
+	if p.quietHours == nil || m.Priority == PriorityEmergency || m.Priority == PriorityLowest {
+		return
+	}
+
+	if p.quietHours.contains(p.now()) {
+		m.Priority = PriorityLowest
+	}
+}
@@ -0,0 +1,117 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// cacheLimit keeps the app limits of the last message sent.
+func (p *Pushover) cacheLimit(limit *Limit) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.limit = limit
+}
+
+// CanSend returns true if the remaining quota known from the last message
+// sent allows n more messages. The quota is reset to its total after the
+// reset time. Without any message sent yet, it returns false unless the app
+// is created WithOptimisticQuota.
+func (p *Pushover) CanSend(n int) bool {
+	p.mu.Lock()
+	limit := p.limit
+	p.mu.Unlock()
+
+	if limit == nil {
+		return p.optimisticQuota
+	}
+
+	if !p.now().Before(limit.NextReset) {
+		return n <= limit.Total
+	}
+
+	return n <= limit.Remaining
+}
+
+// quotaBreakerOpen returns true if the message must not be sent because the
+// quota is exhausted until its next reset, see WithQuotaCircuitBreaker. The
+// emergency messages are always sent.
+func (p *Pushover) quotaBreakerOpen(m *Message) bool {
+	if !p.quotaBreaker || m.Priority == PriorityEmergency {
+		return false
+	}
+
+	p.mu.Lock()
+	limit := p.limit
+	p.mu.Unlock()
+
+	return limit != nil && limit.Remaining <= 0 && p.now().Before(limit.NextReset)
+}
+
+// downgrade lowers the high priority of the message to normal if the
+// remaining quota is under the auto downgrade threshold, it returns true if
+// the message was downgraded. The emergency priority is never touched.
+func (p *Pushover) downgrade(m *Message) bool {
+	if p.downgradeThreshold <= 0 || m.Priority != PriorityHigh {
+		return false
+	}
+
+	p.mu.Lock()
+	limit := p.limit
+	p.mu.Unlock()
+
+	// The quota is unknown or reset
+	if limit == nil || !p.now().Before(limit.NextReset) {
+		return false
+	}
+
+	if limit.Remaining >= p.downgradeThreshold {
+		return false
+	}
+
+	m.Priority = PriorityNormal
+	return true
+}
+
+// GetAppLimits returns the app limits without sending a message, e.g. to
+// check the monthly budget on a schedule. The limits are kept for CanSend.
+func (p *Pushover) GetAppLimits(ctx context.Context) (*Limit, error) {
+	url := fmt.Sprintf("%s/apps/limits.json?token=%s", APIEndpoint, p.token)
+
+	// Validate pushover
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, p.redactError(err)
+	}
+	req = req.WithContext(ctx)
+
+	var response struct {
+		Status    int    `json:"status"`
+		Limit     int    `json:"limit"`
+		Remaining int    `json:"remaining"`
+		Reset     int64  `json:"reset"`
+		Errors    Errors `json:"errors"`
+	}
+	if err := p.do(req, &response, false); err != nil {
+		return nil, err
+	}
+
+	if response.Status != 1 {
+		return nil, p.redactError(response.Errors)
+	}
+
+	limit := &Limit{
+		Total:     response.Limit,
+		Remaining: response.Remaining,
+		NextReset: time.Unix(response.Reset, 0),
+	}
+	p.cacheLimit(limit)
+
+	return limit, nil
+}
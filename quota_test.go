@@ -0,0 +1,199 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCanSend tests the quota check from the cached limits
+func TestCanSend(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+
+	// The fake server returns 6000 remaining messages out of 7500 until
+	// 1393653600
+	beforeReset := func() time.Time { return time.Unix(1393653599, 0) }
+	afterReset := func() time.Time { return time.Unix(1393653600, 0) }
+
+	tt := []struct {
+		name     string
+		opts     []Option
+		send     bool
+		n        int
+		expected bool
+	}{
+		{"unknown quota", nil, false, 1, false},
+		{"unknown optimistic quota", []Option{WithOptimisticQuota()}, false, 1, true},
+		{"within quota", []Option{WithClock(beforeReset)}, true, 6000, true},
+		{"over quota", []Option{WithClock(beforeReset)}, true, 6001, false},
+		{"after reset", []Option{WithClock(afterReset)}, true, 7500, true},
+		{"over total after reset", []Option{WithClock(afterReset)}, true, 7501, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			if tc.send {
+				if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+			}
+
+			if got := p.CanSend(tc.n); got != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestGetAppLimits tests the app limits fetched without sending
+func TestGetAppLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apps/limits.json" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+
+		fmt.Fprintln(w, `{"limit":10000,"remaining":7496,"reset":1393653600,"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	p := New(fakePushover.token, WithClock(func() time.Time { return time.Unix(1393653599, 0) }))
+	got, err := p.GetAppLimits(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	expected := &Limit{Total: 10000, Remaining: 7496, NextReset: time.Unix(1393653600, 0)}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	if !p.CanSend(7496) || p.CanSend(7497) {
+		t.Fatalf("the limits should be kept for CanSend")
+	}
+}
+
+// TestAutoDowngrade tests the high priority downgraded when the quota is low
+func TestAutoDowngrade(t *testing.T) {
+	var priorities []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priorities = append(priorities, r.FormValue("priority"))
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "100")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","receipt":"KAWXTswy4cekx6vZbHBKbCKk1c1fdf"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	beforeReset := func() time.Time { return time.Unix(1393653599, 0) }
+
+	tt := []struct {
+		name               string
+		threshold          int
+		message            *Message
+		expectedPriority   string
+		expectedDowngraded bool
+	}{
+		{"over threshold", 50, NewHighPriorityMessage("Hello"), "1", false},
+		{"under threshold", 500, NewHighPriorityMessage("Hello"), "0", true},
+		{"emergency", 500, NewEmergencyMessage("Hello", time.Minute, time.Hour), "2", false},
+		{"low priority", 500, NewLowPriorityMessage("Hello"), "-1", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, WithClock(beforeReset), WithAutoDowngrade(tc.threshold))
+
+			// The first message gets the quota
+			if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			priorities = nil
+			response, err := p.SendMessage(tc.message, fakeRecipient)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			if priorities[0] != tc.expectedPriority {
+				t.Fatalf("expected priority %q, got %q", tc.expectedPriority, priorities[0])
+			}
+
+			if response.Downgraded != tc.expectedDowngraded {
+				t.Fatalf("expected downgraded %t, got %t", tc.expectedDowngraded, response.Downgraded)
+			}
+		})
+	}
+}
+
+// TestQuotaCircuitBreaker tests the messages rejected while the quota is
+// exhausted
+func TestQuotaCircuitBreaker(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "0")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		if r.FormValue("priority") != "2" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["application has exceeded its monthly message limit"]}`)
+			return
+		}
+
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","receipt":"KAWXTswy4cekx6vZbHBKbCKk1c1fdf"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	now := time.Unix(1393653599, 0)
+	clock := func() time.Time { return now }
+
+	tt := []struct {
+		name             string
+		opts             []Option
+		message          *Message
+		afterReset       bool
+		expectedRequests int32
+		expectedErr      error
+	}{
+		{"open", []Option{WithQuotaCircuitBreaker()}, NewMessage("Hello"), false, 0, ErrQuotaExceeded},
+		{"emergency", []Option{WithQuotaCircuitBreaker()}, NewEmergencyMessage("Hello", time.Minute, time.Hour), false, 1, nil},
+		{"closed after reset", []Option{WithQuotaCircuitBreaker()}, NewMessage("Hello"), true, 1, ErrQuotaExceeded},
+		{"without the option", nil, NewMessage("Hello"), false, 1, ErrQuotaExceeded},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			now = time.Unix(1393653599, 0)
+			p := New(fakePushover.token, append(tc.opts, WithClock(clock))...)
+
+			// The first message gets the exhausted quota
+			if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != ErrQuotaExceeded {
+				t.Fatalf("expected %v, got %v", ErrQuotaExceeded, err)
+			}
+
+			if tc.afterReset {
+				now = time.Unix(1393653600, 0)
+			}
+
+			atomic.StoreInt32(&requests, 0)
+			if _, err := p.SendMessage(tc.message, fakeRecipient); err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if got := atomic.LoadInt32(&requests); got != tc.expectedRequests {
+				t.Fatalf("expected %d requests, got %d", tc.expectedRequests, got)
+			}
+		})
+	}
+}
@@ -9,37 +9,56 @@ import (
 // ReceiptDetails represents the receipt informations in case of emergency
 // priority.
 type ReceiptDetails struct {
-	Status          int
-	Acknowledged    bool
-	AcknowledgedBy  string
-	Expired         bool
-	CalledBack      bool
-	ID              string
-	AcknowledgedAt  *time.Time
-	LastDeliveredAt *time.Time
-	ExpiresAt       *time.Time
-	CalledBackAt    *time.Time
+	Status         int
+	Acknowledged   bool
+	AcknowledgedBy string
+	// AcknowledgedByDevice is the device of the user who acknowledged, e.g.
+	// a member of the group the notification was sent to.
+	AcknowledgedByDevice string
+	Expired              bool
+	CalledBack           bool
+	ID                   string
+	AcknowledgedAt       *time.Time
+	LastDeliveredAt      *time.Time
+	ExpiresAt            *time.Time
+	CalledBackAt         *time.Time
 }
 
 // UnmarshalJSON is a custom unmarshal function to handle timestamps and
 // boolean as int and convert them to the right type.
 func (r *ReceiptDetails) UnmarshalJSON(data []byte) error {
+	return r.unmarshalJSON(data, false)
+}
+
+// unmarshalJSONStrict unmarshals the receipt details rejecting the unknown
+// fields.
+func (r *ReceiptDetails) unmarshalJSONStrict(data []byte) error {
+	return r.unmarshalJSON(data, true)
+}
+
+func (r *ReceiptDetails) unmarshalJSON(data []byte, strict bool) error {
 	dataBytes := bytes.NewReader(data)
 	var aux struct {
-		ID              string     `json:"request"`
-		Status          int        `json:"status"`
-		Acknowledged    intBool    `json:"acknowledged"`
-		AcknowledgedBy  string     `json:"acknowledged_by"`
-		Expired         intBool    `json:"expired"`
-		CalledBack      intBool    `json:"called_back"`
-		AcknowledgedAt  *timestamp `json:"acknowledged_at"`
-		LastDeliveredAt *timestamp `json:"last_delivered_at"`
-		ExpiresAt       *timestamp `json:"expires_at"`
-		CalledBackAt    *timestamp `json:"called_back_at"`
+		ID                   string    `json:"request"`
+		Status               int       `json:"status"`
+		Acknowledged         intBool   `json:"acknowledged"`
+		AcknowledgedBy       string    `json:"acknowledged_by"`
+		AcknowledgedByDevice string    `json:"acknowledged_by_device"`
+		Expired              intBool   `json:"expired"`
+		CalledBack           intBool   `json:"called_back"`
+		AcknowledgedAt       timestamp `json:"acknowledged_at"`
+		LastDeliveredAt      timestamp `json:"last_delivered_at"`
+		ExpiresAt            timestamp `json:"expires_at"`
+		CalledBackAt         timestamp `json:"called_back_at"`
+	}
+
+	// Decode json into the aux struct, the absent timestamps stay nil
+	decoder := json.NewDecoder(dataBytes)
+	if strict {
+		decoder.DisallowUnknownFields()
 	}
 
-	// Decode json into the aux struct
-	if err := json.NewDecoder(dataBytes).Decode(&aux); err != nil {
+	if err := decoder.Decode(&aux); err != nil {
 		return err
 	}
 
@@ -47,6 +66,7 @@ func (r *ReceiptDetails) UnmarshalJSON(data []byte) error {
 	r.Status = aux.Status
 	r.Acknowledged = bool(aux.Acknowledged)
 	r.AcknowledgedBy = aux.AcknowledgedBy
+	r.AcknowledgedByDevice = aux.AcknowledgedByDevice
 	r.Expired = bool(aux.Expired)
 	r.CalledBack = bool(aux.CalledBack)
 	r.ID = aux.ID
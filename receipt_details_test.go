@@ -1,6 +1,17 @@
 package pushover
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
 
 // TestEmptyReceiptDetails tests if the receipt is empty trying to get details
 func TestEmptyReceiptDetails(t *testing.T) {
@@ -14,3 +25,242 @@ func TestEmptyReceiptDetails(t *testing.T) {
 		t.Errorf("Should get an ErrEmptyReceipt")
 	}
 }
+
+// TestPollReceiptFunc tests that the polling stops once acknowledged
+func TestPollReceiptFunc(t *testing.T) {
+	// The receipt is acknowledged on the third poll
+	var polls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acknowledged := 0
+		if atomic.AddInt32(&polls, 1) >= 3 {
+			acknowledged = 1
+		}
+
+		fmt.Fprintf(w, `{"status":1,"acknowledged":%d,"acknowledged_at":0,"last_delivered_at":0,`+
+			`"expired":0,"expires_at":0,"called_back":0,"called_back_at":0,`+
+			`"request":"e95f35c2d75a100a3719b3764f0c8e47"}`, acknowledged)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	calls := 0
+	err := fakePushover.PollReceiptFunc(context.Background(), "receipt", time.Millisecond, func(d *ReceiptDetails) bool {
+		calls++
+		return d.Acknowledged
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+}
+
+// TestPollReceiptFuncErrors tests the errors while polling a receipt
+func TestPollReceiptFuncErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, fakeReceiptBody)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	never := func(*ReceiptDetails) bool { return false }
+
+	tt := []struct {
+		name        string
+		ctx         context.Context
+		receipt     string
+		interval    time.Duration
+		expectedErr error
+	}{
+		{"invalid interval", context.Background(), "receipt", 0, ErrInvalidPollInterval},
+		{"empty receipt", context.Background(), "", time.Millisecond, ErrEmptyReceipt},
+		{"context done", ctx, "receipt", time.Millisecond, context.DeadlineExceeded},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fakePushover.PollReceiptFunc(tc.ctx, tc.receipt, tc.interval, never)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected %q, got %q", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestSendEmergency tests the emergency message sent along with its receipt
+// details
+func TestSendEmergency(t *testing.T) {
+	receipt := "KAWXTswy4cekx6vZbHBKbCKk1c1fdf"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/receipts/"+receipt+".json" {
+			fmt.Fprint(w, `{"status":1,"acknowledged":0,"acknowledged_at":0,"last_delivered_at":1393653600,`+
+				`"expired":0,"expires_at":1393657200,"called_back":0,"called_back_at":0,`+
+				`"request":"e95f35c2d75a100a3719b3764f0c8e47"}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintf(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6","receipt":"%s"}`, receipt)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	if _, err := fakePushover.SendEmergency(context.Background(), NewMessage("Hello"), fakeRecipient); err != ErrNotEmergency {
+		t.Fatalf("expected %v, got %v", ErrNotEmergency, err)
+	}
+
+	message := NewEmergencyMessage("Hello", time.Minute, time.Hour)
+	details, err := fakePushover.SendEmergency(context.Background(), message, fakeRecipient)
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	if details.ExpiresAt == nil || details.ExpiresAt.Unix() != 1393657200 {
+		t.Fatalf("unexpected receipt details %+v", details)
+	}
+
+	// The messages without a priority are sent with the app default priority
+	p := New(fakePushover.token, WithDefaultPriority(PriorityEmergency), WithEmergencyDefaults(time.Minute, time.Hour))
+	if _, err := p.SendEmergency(context.Background(), NewMessage("Hello"), fakeRecipient); err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+}
+
+// TestWatchReceipt tests the receipt details sent until acknowledged
+func TestWatchReceipt(t *testing.T) {
+	// The receipt is acknowledged on the second poll
+	var polls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "failing") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		acknowledged := 0
+		if atomic.AddInt32(&polls, 1) >= 2 {
+			acknowledged = 1
+		}
+
+		fmt.Fprintf(w, `{"status":1,"acknowledged":%d,"acknowledged_at":0,"last_delivered_at":0,`+
+			`"expired":0,"expires_at":0,"called_back":0,"called_back_at":0,`+
+			`"request":"e95f35c2d75a100a3719b3764f0c8e47"}`, acknowledged)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	details, errs := fakePushover.WatchReceipt(context.Background(), "receipt", time.Millisecond)
+	var acknowledged []bool
+	for d := range details {
+		acknowledged = append(acknowledged, d.Acknowledged)
+	}
+
+	if !reflect.DeepEqual(acknowledged, []bool{false, true}) {
+		t.Fatalf("unexpected details %v", acknowledged)
+	}
+
+	if err, ok := <-errs; ok {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	// The poll error stops the polling
+	details, errs = fakePushover.WatchReceipt(context.Background(), "failing", time.Millisecond)
+	if err := <-errs; err != ErrHTTPPushover {
+		t.Fatalf("expected %v, got %v", ErrHTTPPushover, err)
+	}
+
+	if _, ok := <-details; ok {
+		t.Fatalf("expected the details channel to be closed")
+	}
+}
+
+// TestReceiptDetailsUnmarshal tests the receipt details with the empty and
+// absent fields
+func TestReceiptDetailsUnmarshal(t *testing.T) {
+	acknowledgedAt := time.Unix(1393653600, 0)
+
+	tt := []struct {
+		name        string
+		body        string
+		expected    ReceiptDetails
+		expectedErr bool
+	}{
+		{
+			name: "acknowledged by a group member",
+			body: `{"status":1,"acknowledged":1,"acknowledged_at":1393653600,"acknowledged_by":"uQiRzpo4DXghDmr9QzzfQu27cmVRsG",` +
+				`"acknowledged_by_device":"iphone","last_delivered_at":"1393653600","request":"e95f35c2d75a100a3719b3764f0c8e47"}`,
+			expected: ReceiptDetails{
+				Status:               1,
+				Acknowledged:         true,
+				AcknowledgedBy:       "uQiRzpo4DXghDmr9QzzfQu27cmVRsG",
+				AcknowledgedByDevice: "iphone",
+				AcknowledgedAt:       &acknowledgedAt,
+				LastDeliveredAt:      &acknowledgedAt,
+				ID:                   "e95f35c2d75a100a3719b3764f0c8e47",
+			},
+		},
+		{
+			name: "empty fields",
+			body: `{"status":1,"acknowledged":0,"acknowledged_at":0,"acknowledged_by":"","acknowledged_by_device":"",` +
+				`"last_delivered_at":"","expires_at":null,"request":"e95f35c2d75a100a3719b3764f0c8e47"}`,
+			expected: ReceiptDetails{Status: 1, ID: "e95f35c2d75a100a3719b3764f0c8e47"},
+		},
+		{
+			name:     "absent fields",
+			body:     `{"status":1,"request":"e95f35c2d75a100a3719b3764f0c8e47"}`,
+			expected: ReceiptDetails{Status: 1, ID: "e95f35c2d75a100a3719b3764f0c8e47"},
+		},
+		{
+			name:        "invalid timestamp",
+			body:        `{"status":1,"acknowledged_at":"yesterday"}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var got ReceiptDetails
+			err := got.UnmarshalJSON([]byte(tc.body))
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestReceiptNotFound tests the unknown receipts
+func TestReceiptNotFound(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"receipt":"not found","errors":["receipt not found; may be invalid or expired"],`+
+			`"status":0,"request":"e95f35c2d75a100a3719b3764f0c8e47"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	if _, err := fakePushover.GetReceiptDetails("receipt"); err != ErrReceiptNotFound {
+		t.Fatalf("expected %v, got %v", ErrReceiptNotFound, err)
+	}
+
+	never := func(*ReceiptDetails) bool { return false }
+	if err := fakePushover.PollReceiptFunc(context.Background(), "receipt", time.Millisecond, never); err != ErrReceiptNotFound {
+		t.Fatalf("expected %v, got %v", ErrReceiptNotFound, err)
+	}
+}
@@ -1,6 +1,11 @@
 package pushover
 
-import "regexp"
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
 
 var recipientRegexp *regexp.Regexp
 
@@ -18,6 +23,12 @@ func NewRecipient(token string) *Recipient {
 	return &Recipient{token}
 }
 
+// ValidUserKey reports whether s is formatted as a user or group key, it
+// doesn't check that the key exists.
+func ValidUserKey(s string) bool {
+	return recipientRegexp.MatchString(s)
+}
+
 // Validates recipient token.
 func (r *Recipient) validate() error {
 	// Check empty token
@@ -26,7 +37,7 @@ func (r *Recipient) validate() error {
 	}
 
 	// Check invalid token
-	if recipientRegexp.MatchString(r.token) == false {
+	if !ValidUserKey(r.token) {
 		return ErrInvalidRecipientToken
 	}
 	return nil
@@ -41,3 +52,36 @@ type RecipientDetails struct {
 	RequestID string   `json:"request"`
 	Errors    Errors   `json:"errors"`
 }
+
+// preflight validates the recipient with the API before the first message
+// sent to it, see WithPreflightValidation. The network errors are not cached.
+func (p *Pushover) preflight(ctx context.Context, recipient *Recipient) error {
+	if !p.preflightValidation {
+		return nil
+	}
+
+	p.mu.Lock()
+	err, ok := p.preflightResults[recipient.token]
+	p.mu.Unlock()
+	if ok {
+		return err
+	}
+
+	details, err := p.getRecipientDetails(ctx, recipient)
+	if err != nil {
+		return err
+	}
+
+	if details.Status != 1 {
+		err = fmt.Errorf("%w: %s", ErrInvalidRecipient, strings.Join(details.Errors, ", "))
+	}
+
+	p.mu.Lock()
+	if p.preflightResults == nil {
+		p.preflightResults = map[string]error{}
+	}
+	p.preflightResults[recipient.token] = err
+	p.mu.Unlock()
+
+	return err
+}
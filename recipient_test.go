@@ -25,3 +25,30 @@ func TestRecipientTokenFormat(t *testing.T) {
 		})
 	}
 }
+
+// TestValidKeys tests the exported token and user key format checks
+func TestValidKeys(t *testing.T) {
+	tt := []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{"empty", "", false},
+		{"truncated", "uQiRzpo4DXghDmr9QzzfQu27cmVRs", false},
+		{"too long", "uQiRzpo4DXghDmr9QzzfQu27cmVRsGa", false},
+		{"invalid character", "uQiR-po4DXghDmr9QzzfQu27cmVRsG", false},
+		{"valid", "uQiRzpo4DXghDmr9QzzfQu27cmVRsG", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ValidToken(tc.key); got != tc.expected {
+				t.Errorf("ValidToken: expected %t, got %t", tc.expected, got)
+			}
+
+			if got := ValidUserKey(tc.key); got != tc.expected {
+				t.Errorf("ValidUserKey: expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
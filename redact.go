@@ -0,0 +1,44 @@
+package pushover
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactedSecret replaces the secrets in the errors.
+const redactedSecret = "<redacted>"
+
+// redact removes the app token and the given recipient tokens from a string.
+func (p *Pushover) redact(s string, rTokens ...string) string {
+	for _, secret := range append([]string{p.token}, rTokens...) {
+		if secret != "" {
+			s = strings.Replace(s, secret, redactedSecret, -1)
+		}
+	}
+
+	return s
+}
+
+// redactError removes the app token and the given recipient tokens from an
+// error while keeping its type, the errors with a static message are returned
+// untouched.
+func (p *Pushover) redactError(err error, rTokens ...string) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *url.Error:
+		return &url.Error{
+			Op:  e.Op,
+			URL: p.redact(e.URL, rTokens...),
+			Err: p.redactError(e.Err, rTokens...),
+		}
+	case Errors:
+		ret := make(Errors, len(e))
+		for i, s := range e {
+			ret[i] = p.redact(s, rTokens...)
+		}
+		return ret
+	default:
+		return err
+	}
+}
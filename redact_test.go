@@ -0,0 +1,89 @@
+package pushover
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestErrorsDoNotLeakSecrets tests that the tokens never end up in an error
+func TestErrorsDoNotLeakSecrets(t *testing.T) {
+	secrets := []string{fakePushover.token, fakeRecipient.token}
+
+	// The fake server echoes the secrets in its errors
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["token %s is invalid", "user %s is invalid"]}`,
+			fakePushover.token, fakeRecipient.token)
+	}))
+	defer ts.Close()
+
+	// The closed server makes the http client fail with an error containing
+	// the requested URL
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	tt := []struct {
+		name     string
+		endpoint string
+		call     func() error
+	}{
+		{"send message", ts.URL, func() error {
+			_, err := fakePushover.SendMessage(NewMessage("Hello"), fakeRecipient)
+			return err
+		}},
+		{"receipt details", closed.URL, func() error {
+			_, err := fakePushover.GetReceiptDetails("receipt")
+			return err
+		}},
+		{"recipient details", closed.URL, func() error {
+			_, err := fakePushover.GetRecipientDetails(fakeRecipient)
+			return err
+		}},
+		{"cancel notification", closed.URL, func() error {
+			_, err := fakePushover.CancelEmergencyNotification("receipt")
+			return err
+		}},
+		{"send message on a closed server", closed.URL, func() error {
+			_, err := fakePushover.SendMessage(NewMessage("Hello"), fakeRecipient)
+			return err
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = tc.endpoint
+			err := tc.call()
+			if err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+
+			for _, secret := range secrets {
+				if strings.Contains(err.Error(), secret) {
+					t.Fatalf("the error %q contains a secret", err)
+				}
+			}
+		})
+	}
+}
+
+// TestErrorsKeepReceipts tests that the receipts are not mistaken for
+// secrets in the errors
+func TestErrorsKeepReceipts(t *testing.T) {
+	receipt := "KAWXTswy4cekx6vZbHBKbCKk1c1fdf"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["receipt %s not found"]}`, receipt)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	_, err := fakePushover.CancelEmergencyNotification(receipt)
+	if err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), receipt) {
+		t.Fatalf("the error %q should contain the receipt", err)
+	}
+}
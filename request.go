@@ -1,15 +1,101 @@
 package pushover
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
 	"strings"
 )
 
-// do is a generic function to send a request to the API.
-func do(req *http.Request, resType interface{}, returnHeaders bool) error {
-	client := http.DefaultClient
+// Do sends a custom request to the API, the path is relative to the
+// APIEndpoint. The app token is added to the query string, the body is sent
+// untouched with the given content type. The JSON response is decoded into
+// v, a *Response gets its status checked. This is an escape hatch for the
+// requests the typed API can't express.
+func (p *Pushover) Do(ctx context.Context, method, path, contentType string, body io.Reader, v interface{}) error {
+	endpoint, err := url.Parse(APIEndpoint + "/" + strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return p.redactError(err)
+	}
+
+	query := endpoint.Query()
+	query.Set("token", p.token)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, endpoint.String(), body)
+	if err != nil {
+		return p.redactError(err)
+	}
+	req = req.WithContext(ctx)
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return p.do(req, v, false)
+}
+
+// do is a generic function to send a request to the API. The app token and
+// the given recipient tokens are redacted from the returned errors and the
+// captured requests.
+func (p *Pushover) do(req *http.Request, resType interface{}, returnHeaders bool, rTokens ...string) error {
+	err := p.redactError(p.doRequest(req, resType, returnHeaders, rTokens), rTokens...)
+
+	// The API errors are kept in the response, redacted as well
+	if errs, ok := err.(Errors); ok {
+		if r, ok := resType.(*Response); ok {
+			r.Errors = errs
+		}
+	}
+
+	return err
+}
+
+// doRequest sends the request and decodes the response.
+func (p *Pushover) doRequest(req *http.Request, resType interface{}, returnHeaders bool, rTokens []string) error {
+	if !p.acquire() {
+		return ErrClosed
+	}
+	defer p.release()
+
+	// Abort the request when the app context is done or on CancelAll
+	ctx, cancel := p.mergeContext(req.Context())
+	defer cancel()
+	defer p.track(cancel)()
+
+	if p.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, p.timeout)
+		defer cancelTimeout()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	if p.captureLastRequest {
+		if err := p.captureRequest(req, rTokens); err != nil {
+			return err
+		}
+	}
+
+	return p.retry(req, func(req *http.Request) error {
+		return p.doOnce(req, resType, returnHeaders)
+	})
+}
+
+// doOnce sends the request once and decodes the response.
+func (p *Pushover) doOnce(req *http.Request, resType interface{}, returnHeaders bool) error {
+	client := p.client
+	if client == nil {
+		client = http.DefaultClient
+	}
 
 	// Send request
 	resp, err := client.Do(req)
@@ -23,19 +109,51 @@ func do(req *http.Request, resType interface{}, returnHeaders bool) error {
 		return ErrHTTPPushover
 	}
 
+	body, err := readBody(resp)
+	if err != nil {
+		return err
+	}
+
+	// The monthly quota can't be retried before the next reset
+	if resp.StatusCode == http.StatusTooManyRequests && isQuotaExceeded(body) {
+		if limit, err := newLimit(resp.Header); err == nil {
+			p.cacheLimit(limit)
+		}
+		return ErrQuotaExceeded
+	}
+
+	// The unknown receipts, e.g. expired beyond the retention, can't be polled
+	if resp.StatusCode == http.StatusNotFound && isReceiptNotFound(body) {
+		return ErrReceiptNotFound
+	}
+
 	// Decode the JSON response
-	if err := json.NewDecoder(resp.Body).Decode(&resType); err != nil {
+	if err := json.Unmarshal(body, &resType); err != nil {
 		return err
 	}
 
+	// Check the unknown fields of the successful responses only, the error
+	// responses come with fields describing the errors
+	if p.strictDecoding {
+		if err := decodeStrict(body, resType); err != nil {
+			return err
+		}
+	}
+
 	// Check if the unmarshaled data is a response
 	r, ok := resType.(*Response)
 	if !ok {
 		return nil
 	}
 
+	r.HTTPStatus = resp.StatusCode
+	if p.captureRawResponse {
+		r.Raw = body
+	}
+
 	// Check response status
 	if r.Status != 1 {
+		r.FieldErrors = parseFieldErrors(body)
 		return r.Errors
 	}
 
@@ -47,11 +165,138 @@ func do(req *http.Request, resType interface{}, returnHeaders bool) error {
 			return err
 		}
 		r.Limit = appLimits
+		p.cacheLimit(appLimits)
+	}
+
+	return nil
+}
+
+// readBody reads the response body. The gzip responses are decompressed by
+// the transport, unless the Accept-Encoding header was set by a custom
+// transport, they're decompressed here then.
+func readBody(resp *http.Response) ([]byte, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(resp.Body)
+	}
+
+	r, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// isQuotaExceeded returns true if the errors of the response body report the
+// app monthly quota exhaustion, the transient rate limiting is not.
+func isQuotaExceeded(body []byte) bool {
+	var response struct {
+		Errors Errors `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+
+	for _, e := range response.Errors {
+		e = strings.ToLower(e)
+		if strings.Contains(e, "quota") || strings.Contains(e, "monthly message limit") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isReceiptNotFound returns true if the response body reports an unknown
+// receipt.
+func isReceiptNotFound(body []byte) bool {
+	var response struct {
+		Receipt string `json:"receipt"`
+		Errors  Errors `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+
+	if response.Receipt == "not found" {
+		return true
+	}
+
+	for _, e := range response.Errors {
+		if strings.Contains(strings.ToLower(e), "receipt") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// captureRequest keeps a dump of the request with the tokens redacted.
+func (p *Pushover) captureRequest(req *http.Request, rTokens []string) error {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return err
 	}
 
+	dump = []byte(p.redact(string(dump), rTokens...))
+
+	p.mu.Lock()
+	p.lastRequest = dump
+	p.mu.Unlock()
+
 	return nil
 }
 
+// strictUnmarshaler is implemented by the types with a custom unmarshal
+// function, to reject the unknown fields.
+type strictUnmarshaler interface {
+	unmarshalJSONStrict(data []byte) error
+}
+
+// decodeStrict decodes a successful response rejecting the unknown fields.
+func decodeStrict(body []byte, resType interface{}) error {
+	var status struct {
+		Status int `json:"status"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil || status.Status != 1 {
+		return nil
+	}
+
+	if u, ok := resType.(strictUnmarshaler); ok {
+		return u.unmarshalJSONStrict(body)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(&resType)
+}
+
+// mergeContext returns a context done when either the given context or the
+// app context is done.
+func (p *Pushover) mergeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if p.ctx == nil {
+		return ctx, cancel
+	}
+
+	// Cancel right away if the app context is already done
+	if p.ctx.Err() != nil {
+		cancel()
+		return ctx, cancel
+	}
+
+	go func() {
+		select {
+		case <-p.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
 // urlEncodedRequest returns a new url encoded request.
 func newURLEncodedRequest(method, endpoint string, params map[string]string) (*http.Request, error) {
 	urlValues := url.Values{}
@@ -0,0 +1,60 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDo tests a custom request
+func TestDo(t *testing.T) {
+	body := `{"custom":"body"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method != "POST":
+			t.Errorf("unexpected method %s", r.Method)
+		case r.URL.Path != "/custom/endpoint.json":
+			t.Errorf("unexpected path %s", r.URL.Path)
+		case r.URL.Query().Get("token") != fakePushover.token:
+			t.Errorf("the token should be in the query string")
+		case r.Header.Get("Content-Type") != "application/json":
+			t.Errorf("unexpected content type %s", r.Header.Get("Content-Type"))
+		case string(got) != body:
+			t.Errorf("the body should be untouched, got %q", got)
+		}
+
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	response := &Response{}
+	err := fakePushover.Do(context.Background(), "POST", "/custom/endpoint.json",
+		"application/json", strings.NewReader(body), response)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if response.ID != "e460545a8b333d0da2f3602aff3133d6" {
+		t.Fatalf("unexpected response %v", response)
+	}
+}
+
+// TestDoErrors tests the status check of a custom request
+func TestDoErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["error1"]}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	err := fakePushover.Do(context.Background(), "GET", "custom.json", "", nil, &Response{})
+	if err == nil || err.Error() != (Errors{"error1"}).Error() {
+		t.Fatalf("expected the API errors, got %v", err)
+	}
+}
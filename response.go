@@ -1,25 +1,182 @@
 package pushover
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Response represents a response from the API.
 type Response struct {
-	Status  int    `json:"status"`
-	ID      string `json:"request"`
-	Errors  Errors `json:"errors"`
-	Receipt string `json:"receipt"`
-	Limit   *Limit
+	Status     int    `json:"status"`
+	ID         string `json:"request"`
+	Errors     Errors `json:"errors"`
+	Receipt    string `json:"receipt"`
+	Info       Info   `json:"info"`
+	HTTPStatus int    `json:"-"`
+	Limit      *Limit
+	// CorrelationID is the local id of the send given with
+	// ContextWithCorrelationID, it's not sent to the API.
+	CorrelationID string `json:"-"`
+	// AttachmentDropped is true if the message was sent again without its
+	// attachment, see WithAttachmentFallback.
+	AttachmentDropped bool `json:"-"`
+	// Downgraded is true if the message was sent with a normal priority
+	// instead of a high one, see WithAutoDowngrade.
+	Downgraded bool `json:"-"`
+	// FallbackUsed is true if the message was sent to the fallback recipient
+	// after being rejected for the recipient, see WithFallbackRecipient.
+	FallbackUsed bool `json:"-"`
+	// Queued is true if the message was added to the outbox to be sent
+	// later, see SendViaOutbox.
+	Queued bool `json:"-"`
+	// Deduped is true if the message wasn't sent, being a duplicate of a
+	// message sent within the dedupe window, see WithDedupeWindow.
+	Deduped bool `json:"-"`
+	// Skipped is true if the message wasn't sent, its condition being false,
+	// see SendIf.
+	Skipped bool `json:"-"`
+	// FieldErrors maps the fields rejected by the API to their errors, e.g.
+	// "user" to "invalid", along with Errors.
+	FieldErrors map[string][]string `json:"-"`
+	// Raw is the response body, e.g. to read the fields not modeled yet, see
+	// WithCaptureRawResponse.
+	Raw []byte `json:"-"`
+
+	// The message sent, to send it again with Resend
+	message   *Message
+	recipient *Recipient
+}
+
+// responseOnError returns the response along with the API errors, a logical
+// failure still comes with a request ID worth logging. The response is nil
+// for any other error.
+func responseOnError(r *Response, err error) (*Response, error) {
+	if _, ok := err.(Errors); ok {
+		return r, err
+	}
+
+	return nil, err
+}
+
+// responseFields are the fields of a response which are not field errors.
+var responseFields = map[string]bool{
+	"status":  true,
+	"request": true,
+	"errors":  true,
+	"receipt": true,
+	"info":    true,
+}
+
+// parseFieldErrors returns the field errors of an error response body, the
+// errors of a field are given as a string or a list of strings, like the
+// info.
+func parseFieldErrors(body []byte) map[string][]string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+
+	var ret map[string][]string
+	for name, value := range fields {
+		if responseFields[name] {
+			continue
+		}
+
+		var errs Info
+		if err := json.Unmarshal(value, &errs); err != nil || len(errs) == 0 {
+			continue
+		}
+
+		if ret == nil {
+			ret = map[string][]string{}
+		}
+		ret[name] = errs
+	}
+
+	return ret
+}
+
+// isAttachmentUnsupported returns true if the API errors report that the
+// attachments aren't supported, e.g. by a gateway.
+func isAttachmentUnsupported(err error) bool {
+	errs, ok := err.(Errors)
+	if !ok {
+		return false
+	}
+
+	for _, e := range errs {
+		e = strings.ToLower(e)
+		if strings.Contains(e, "attachment") &&
+			(strings.Contains(e, "not supported") || strings.Contains(e, "unsupported")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRecipientRejected returns true if the API errors report that the
+// recipient is invalid, e.g. a disabled user, rather than the message.
+func isRecipientRejected(response *Response, err error) bool {
+	errs, ok := err.(Errors)
+	if !ok {
+		return false
+	}
+
+	if response != nil && len(response.FieldErrors["user"]) > 0 {
+		return true
+	}
+
+	for _, e := range errs.AsErrors() {
+		if errors.Is(e, ErrInvalidRecipientToken) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // String represents a printable form of the response.
 func (r Response) String() string {
 	ret := fmt.Sprintf("Request id: %s\n", r.ID)
+	if r.CorrelationID != "" {
+		ret += fmt.Sprintf("Correlation id: %s\n", r.CorrelationID)
+	}
 	if r.Receipt != "" {
 		ret += fmt.Sprintf("Receipt: %s\n", r.Receipt)
 	}
+	if len(r.Info) > 0 {
+		ret += fmt.Sprintf("Info: %s\n", strings.Join(r.Info, ", "))
+	}
 	if r.Limit != nil {
 		ret += fmt.Sprintf("Usage %d/%d messages\nNext reset : %s",
 			r.Limit.Remaining, r.Limit.Total, r.Limit.NextReset)
 	}
 	return ret
 }
+
+// Info represents the advisory messages returned by the API along with a
+// successful response.
+type Info []string
+
+// UnmarshalJSON is a custom unmarshal function to handle the info returned
+// either as a string or as a list of strings.
+func (i *Info) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if s != "" {
+			*i = Info{s}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*i = Info(list)
+	return nil
+}
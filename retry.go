@@ -0,0 +1,133 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// retry sends the request until it succeeds, fails with an error that can't
+// be retried or runs out of retries. The delay between the attempts grows
+// exponentially.
+func (p *Pushover) retry(req *http.Request, send func(*http.Request) error) error {
+	for attempt := 0; ; attempt++ {
+		err := send(req)
+		if err == nil || attempt >= p.maxRetries || !p.isRetryable(err) {
+			return err
+		}
+
+		// The retries of a bulk send share a budget
+		if !takeRetry(req.Context()) {
+			return err
+		}
+		p.stats.retries.Add(1)
+
+		// The body has been consumed, it must be rewound to be sent again
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return err
+			}
+
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return err
+			}
+			req.Body = body
+		}
+
+		select {
+		case <-req.Context().Done():
+			return err
+		case <-time.After(p.backoff(attempt)):
+		}
+	}
+}
+
+// defaultMaxBackoff is the max delay between two retries.
+const defaultMaxBackoff = 30 * time.Second
+
+// retryBudget bounds the number of retries shared by many requests.
+type retryBudget struct {
+	mu        sync.Mutex
+	remaining int
+}
+
+// retryBudgetKey is the context key of the retry budget.
+type retryBudgetKey struct{}
+
+// withRetryBudget returns a context sharing a budget of n retries between
+// the requests using it.
+func withRetryBudget(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, &retryBudget{remaining: n})
+}
+
+// takeRetry returns true if the context retry budget allows another retry,
+// the retries are unbounded without a budget.
+func takeRetry(ctx context.Context) bool {
+	budget, ok := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	if !ok {
+		return true
+	}
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	if budget.remaining <= 0 {
+		return false
+	}
+
+	budget.remaining--
+	return true
+}
+
+// backoff returns the delay to wait before the retry following the given
+// attempt.
+func (p *Pushover) backoff(attempt int) time.Duration {
+	maxBackoff := p.maxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	// The delay is capped, the shift overflow included, a zero backoff
+	// retries right away
+	delay := p.retryBackoff << uint(attempt)
+	if p.retryBackoff > 0 && (delay > maxBackoff || delay>>uint(attempt) != p.retryBackoff) {
+		delay = maxBackoff
+	}
+
+	// Spread the retries of many clients failing at the same time
+	if p.backoffJitter > 0 {
+		delay -= time.Duration(p.backoffJitter * rand.Float64() * float64(delay))
+	}
+
+	return delay
+}
+
+// isRetryable returns true if the request can be retried after the error,
+// using the app retry predicate if any.
+func (p *Pushover) isRetryable(err error) bool {
+	if p.retryPredicate != nil {
+		return p.retryPredicate(err)
+	}
+
+	return isRetryable(err)
+}
+
+// isRetryable returns true if the request failed because of a server error
+// or a network error. The requests aborted by their context are not retried.
+func isRetryable(err error) bool {
+	if err == ErrHTTPPushover {
+		return true
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	_, ok := err.(*url.Error)
+	return ok
+}
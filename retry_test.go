@@ -0,0 +1,264 @@
+package pushover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetries tests that the server errors are retried
+func TestRetries(t *testing.T) {
+	tt := []struct {
+		name             string
+		failures         int
+		opts             []Option
+		expectedAttempts int
+		expectedErr      error
+	}{
+		{"no retries", 1, nil, 1, ErrHTTPPushover},
+		{"successful retry", 2, []Option{WithRetries(3, time.Millisecond)}, 3, nil},
+		{"out of retries", 5, []Option{WithRetries(2, time.Millisecond)}, 3, ErrHTTPPushover},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if r.FormValue("message") != "Hello" {
+					t.Errorf("the body should be sent on each attempt")
+				}
+
+				if attempts <= tc.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				w.Header().Set("X-Limit-App-Limit", "7500")
+				w.Header().Set("X-Limit-App-Remaining", "6000")
+				w.Header().Set("X-Limit-App-Reset", "1393653600")
+				fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != tc.expectedErr {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if attempts != tc.expectedAttempts {
+				t.Fatalf("expected %d attempts, got %d", tc.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+// TestRetriesAPIErrors tests that the API errors are not retried
+func TestRetriesAPIErrors(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		fmt.Fprintln(w, `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["user identifier is invalid"]}`)
+	}))
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	p := New(fakePushover.token, WithRetries(3, time.Millisecond))
+	if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+// TestBackoffJitter tests the backoff delays with jitter
+func TestBackoffJitter(t *testing.T) {
+	tt := []struct {
+		name     string
+		jitter   float64
+		attempt  int
+		minDelay time.Duration
+		maxDelay time.Duration
+	}{
+		{"no jitter", 0, 0, time.Second, time.Second},
+		{"no jitter exponential", 0, 3, 8 * time.Second, 8 * time.Second},
+		{"half jitter", 0.5, 1, time.Second, 2 * time.Second},
+		{"full jitter", 1, 2, 0, 4 * time.Second},
+		{"jitter above 1", 2, 2, 0, 4 * time.Second},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, WithRetries(5, time.Second), WithBackoffJitter(tc.jitter))
+			for i := 0; i < 100; i++ {
+				delay := p.backoff(tc.attempt)
+				if delay < tc.minDelay || delay > tc.maxDelay {
+					t.Fatalf("expected a delay between %s and %s, got %s", tc.minDelay, tc.maxDelay, delay)
+				}
+			}
+		})
+	}
+}
+
+// TestBatchRetryBudget tests that the retries of a bulk send are bounded
+func TestBatchRetryBudget(t *testing.T) {
+	tt := []struct {
+		name             string
+		opts             []Option
+		expectedAttempts int32
+	}{
+		{"no budget", []Option{WithRetries(2, time.Millisecond)}, 12},
+		{"budget", []Option{WithRetries(2, time.Millisecond), WithBatchRetryBudget(3)}, 7},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			recipients := []*Recipient{fakeRecipient, fakeRecipient, fakeRecipient, fakeRecipient}
+			_, summary := p.SendToMany(NewMessage("Hello"), recipients)
+			if summary.Failed != len(recipients) {
+				t.Fatalf("expected %d failures, got %d", len(recipients), summary.Failed)
+			}
+
+			if got := atomic.LoadInt32(&attempts); got != tc.expectedAttempts {
+				t.Fatalf("expected %d attempts, got %d", tc.expectedAttempts, got)
+			}
+		})
+	}
+}
+
+// TestQuotaExceeded tests that the exhausted quota is reported and not
+// retried
+func TestQuotaExceeded(t *testing.T) {
+	tt := []struct {
+		name        string
+		body        string
+		expectedErr error
+	}{
+		{"quota exceeded", `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["application has exceeded its monthly message limit"]}`, ErrQuotaExceeded},
+		{"rate limit", `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["rate limit exceeded, slow down"]}`, Errors{"rate limit exceeded, slow down"}},
+		{"other error", `{"status":0,"request":"e460545a8b333d0da2f3602aff3133d6","errors":["too many requests"]}`, Errors{"too many requests"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprintln(w, tc.body)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, WithRetries(3, time.Millisecond))
+			_, err := p.SendMessage(NewMessage("Hello"), fakeRecipient)
+			if err == nil || err.Error() != tc.expectedErr.Error() {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if attempts != 1 {
+				t.Fatalf("expected 1 attempt, got %d", attempts)
+			}
+		})
+	}
+}
+
+// TestMaxBackoff tests the backoff delays cap
+func TestMaxBackoff(t *testing.T) {
+	tt := []struct {
+		name     string
+		opts     []Option
+		attempt  int
+		expected time.Duration
+	}{
+		{"under default cap", nil, 4, 16 * time.Second},
+		{"default cap", nil, 5, 30 * time.Second},
+		{"shift overflow", nil, 62, 30 * time.Second},
+		{"custom cap", []Option{WithMaxBackoff(5 * time.Second)}, 3, 5 * time.Second},
+		{"zero backoff", []Option{WithRetries(5, 0)}, 3, 0},
+		{"zero backoff shift overflow", []Option{WithRetries(5, 0)}, 62, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := New(fakePushover.token, append([]Option{WithRetries(5, time.Second)}, tc.opts...)...)
+			if got := p.backoff(tc.attempt); got != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestRetryPredicate tests the custom retry eligibility
+func TestRetryPredicate(t *testing.T) {
+	tt := []struct {
+		name             string
+		opts             []Option
+		expectedAttempts int
+	}{
+		{"default predicate", nil, 3},
+		{"no retry on server errors", []Option{WithRetryPredicate(func(err error) bool { return false })}, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer ts.Close()
+
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, append([]Option{WithRetries(2, time.Millisecond)}, tc.opts...)...)
+			if _, err := p.SendMessage(NewMessage("Hello"), fakeRecipient); err != ErrHTTPPushover {
+				t.Fatalf("expected %v, got %v", ErrHTTPPushover, err)
+			}
+
+			if attempts != tc.expectedAttempts {
+				t.Fatalf("expected %d attempts, got %d", tc.expectedAttempts, attempts)
+			}
+		})
+	}
+}
+
+// TestIsRetryable tests the default retry eligibility
+func TestIsRetryable(t *testing.T) {
+	tt := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"server error", ErrHTTPPushover, true},
+		{"network error", &url.Error{Op: "Post", URL: "url", Err: errors.New("connection refused")}, true},
+		{"deadline", &url.Error{Op: "Post", URL: "url", Err: context.DeadlineExceeded}, false},
+		{"canceled", &url.Error{Op: "Post", URL: "url", Err: context.Canceled}, false},
+		{"api error", Errors{"user identifier is invalid"}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.err); got != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package pushover
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SendAt schedules the message to be sent to the recipient at the given
+// time, Pushover has no server side scheduling so the message is kept by the
+// app until then. The message and the recipient are validated right away,
+// the result of the send is reported to the stats and the metrics collector.
+// The returned cancel func stops the pending send, or aborts it if it's in
+// progress, the send is also aborted when the context is done or on
+// CancelAll. Close stops the pending sends and waits for the ones in
+// progress.
+func (p *Pushover) SendAt(ctx context.Context, message *Message, recipient *Recipient, at time.Time) (cancel func(), err error) {
+	return p.SendAtFunc(ctx, message, recipient, at, nil)
+}
+
+// SendAtFunc schedules the message like SendAt, done is then called with the
+// result of the send. It's also called with the context error if the send is
+// stopped before it's sent, e.g. by the cancel func, CancelAll or Close.
+func (p *Pushover) SendAtFunc(ctx context.Context, message *Message, recipient *Recipient, at time.Time, done func(*Response, error)) (cancel func(), err error) {
+	// Validate pushover
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	// Send to the default recipient if none is given
+	if recipient == nil {
+		recipient = p.defaultRecipient
+		if recipient == nil {
+			return nil, ErrEmptyRecipientToken
+		}
+	}
+
+	// Validate recipient
+	if err := recipient.validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate message, unless deferred to the API
+	if !p.skipValidation {
+		prepared := p.prepareMessage(message)
+		if err := prepared.validateWithLimits(p.maxLength(), p.maxTitleLength()); err != nil {
+			return nil, err
+		}
+	}
+
+	// The pending send is waited for by Close
+	if !p.acquire() {
+		return nil, ErrClosed
+	}
+
+	// Keep a copy, the message can be reused by the caller in the meantime
+	scheduled := *message
+
+	ctx, cancelCtx := context.WithCancel(ctx)
+	untrack := p.track(cancelCtx)
+	unschedule := p.schedule(cancelCtx)
+
+	var once sync.Once
+	finish := func(response *Response, err error) {
+		once.Do(func() {
+			defer p.release()
+			untrack()
+			unschedule()
+			cancelCtx()
+
+			if done != nil {
+				done(response, err)
+			}
+		})
+	}
+
+	timer := time.AfterFunc(at.Sub(p.now()), func() {
+		if err := ctx.Err(); err != nil {
+			finish(nil, err)
+			return
+		}
+
+		// Close waits for the send in progress rather than aborting it
+		unschedule()
+		finish(p.SendMessageContext(ctx, &scheduled, recipient))
+	})
+
+	// Stop the pending send when the context is done, e.g. on CancelAll or
+	// Close
+	go func() {
+		<-ctx.Done()
+		if timer.Stop() {
+			finish(nil, ctx.Err())
+		}
+	}()
+
+	return cancelCtx, nil
+}
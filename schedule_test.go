@@ -0,0 +1,141 @@
+package pushover
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// notifyingCollector signals the observed sends
+type notifyingCollector chan SendObservation
+
+func (c notifyingCollector) ObserveSend(o SendObservation) {
+	c <- o
+}
+
+// TestSendAt tests the scheduled sends
+func TestSendAt(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	now := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)
+	collector := make(notifyingCollector, 2)
+	p := New(fakePushover.token, WithMetricsCollector(collector), WithClock(func() time.Time { return now }))
+
+	if _, err := p.SendAt(context.Background(), NewMessage(""), fakeRecipient, now); err != ErrMessageEmpty {
+		t.Fatalf("expected %v, got %v", ErrMessageEmpty, err)
+	}
+
+	message := NewMessage("Reminder")
+	if _, err := p.SendAt(context.Background(), message, fakeRecipient, now.Add(20*time.Millisecond)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The scheduled message is not affected by the later changes
+	message.Message = "Changed"
+
+	select {
+	case o := <-collector:
+		if o.Err != nil || o.MessageLength != len("Reminder") {
+			t.Fatalf("unexpected observation %+v", o)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the message to be sent")
+	}
+
+	cancel, err := p.SendAt(context.Background(), NewMessage("Reminder"), fakeRecipient, now.Add(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	cancel()
+	cancel()
+
+	select {
+	case o := <-collector:
+		t.Fatalf("expected the cancelled message not to be sent, got %+v", o)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestSendAtCancelAll tests that CancelAll stops the pending scheduled sends
+func TestSendAtCancelAll(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	collector := make(notifyingCollector, 1)
+	p := New(fakePushover.token, WithMetricsCollector(collector))
+
+	if _, err := p.SendAt(context.Background(), NewMessage("Reminder"), fakeRecipient, time.Now().Add(20*time.Millisecond)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	p.CancelAll()
+
+	select {
+	case o := <-collector:
+		t.Fatalf("expected the cancelled message not to be sent, got %+v", o)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestSendAtFunc tests the results of the scheduled sends, and the pending
+// sends stopped by Close
+func TestSendAtFunc(t *testing.T) {
+	ts := newFakeBulkServer()
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	p := New(fakePushover.token)
+	type result struct {
+		response *Response
+		err      error
+	}
+	results := make(chan result, 2)
+	done := func(response *Response, err error) {
+		results <- result{response, err}
+	}
+
+	if _, err := p.SendAtFunc(context.Background(), NewMessage("Reminder"), fakeRecipient, time.Now(), done); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	select {
+	case r := <-results:
+		if r.err != nil || r.response == nil || r.response.Status != 1 {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the message to be sent")
+	}
+
+	if _, err := p.SendAtFunc(context.Background(), NewMessage("Reminder"), fakeRecipient, time.Now().Add(time.Hour), done); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Close doesn't wait for the pending send, it's stopped
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("expected Close to stop the pending send")
+	}
+
+	select {
+	case r := <-results:
+		if r.err != context.Canceled {
+			t.Fatalf("expected %v, got %v", context.Canceled, r.err)
+		}
+	default:
+		t.Fatal("expected the stopped send to be reported")
+	}
+
+	if _, err := p.SendAt(context.Background(), NewMessage("Reminder"), fakeRecipient, time.Now()); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
@@ -0,0 +1,131 @@
+package pushover
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// builtInSounds are the sounds available to all the apps.
+var builtInSounds = map[string]bool{
+	SoundPushover:     true,
+	SoundBike:         true,
+	SoundBugle:        true,
+	SoundCashRegister: true,
+	SoundClassical:    true,
+	SoundCosmic:       true,
+	SoundFalling:      true,
+	SoundGamelan:      true,
+	SoundIncoming:     true,
+	SoundIntermission: true,
+	SoundMagic:        true,
+	SoundMechanical:   true,
+	SoundPianobar:     true,
+	SoundSiren:        true,
+	SoundSpaceAlarm:   true,
+	SoundTugBoat:      true,
+	SoundAlien:        true,
+	SoundClimb:        true,
+	SoundPersistent:   true,
+	SoundEcho:         true,
+	SoundUpDown:       true,
+	SoundVibrate:      true,
+	SoundNone:         true,
+}
+
+// Sounds represents the sounds available to the app, mapping their names to
+// their descriptions.
+type Sounds struct {
+	BuiltIn map[string]string
+	// Custom are the sounds uploaded by the user.
+	Custom map[string]string
+}
+
+// GetSounds returns the sounds available to the app, the built-in ones are
+// separated from the custom ones uploaded by the user. The custom sounds are
+// then accepted by the strict validation.
+func (p *Pushover) GetSounds() (*Sounds, error) {
+	url := fmt.Sprintf("%s/sounds.json?token=%s", APIEndpoint, p.token)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, p.redactError(err)
+	}
+
+	var response struct {
+		Status int               `json:"status"`
+		Sounds map[string]string `json:"sounds"`
+		Errors Errors            `json:"errors"`
+	}
+	if err := p.do(req, &response, false); err != nil {
+		return nil, err
+	}
+
+	if response.Status != 1 {
+		return nil, p.redactError(response.Errors)
+	}
+
+	sounds := &Sounds{
+		BuiltIn: map[string]string{},
+		Custom:  map[string]string{},
+	}
+	for name, description := range response.Sounds {
+		if builtInSounds[name] {
+			sounds.BuiltIn[name] = description
+		} else {
+			sounds.Custom[name] = description
+		}
+	}
+
+	p.mu.Lock()
+	p.customSounds = sounds.Custom
+	p.mu.Unlock()
+
+	return sounds, nil
+}
+
+// validateSound returns an ErrUnknownSound in strict mode if the message
+// sound is neither a built-in sound nor a custom sound fetched with
+// GetSounds.
+func (p *Pushover) validateSound(message *Message) error {
+	if !p.strictValidation || message.Sound == "" {
+		return nil
+	}
+
+	if known, _ := p.knownSound(message.Sound); known {
+		return nil
+	}
+
+	return ErrUnknownSound
+}
+
+// knownSound returns true if the sound is built-in or a custom sound fetched
+// with GetSounds. The second value is false if the sounds were never
+// fetched, the custom sounds are then unknown.
+func (p *Pushover) knownSound(sound string) (known, fetched bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, custom := p.customSounds[sound]
+	return builtInSounds[sound] || custom, p.customSounds != nil
+}
+
+// fallbackSound returns the sound to use for the message sound, the first
+// known sound of the fallback chain replaces a sound missing from the sounds
+// fetched with GetSounds.
+func (p *Pushover) fallbackSound(sound string) string {
+	if sound == "" || len(p.soundFallback) == 0 {
+		return sound
+	}
+
+	if known, fetched := p.knownSound(sound); known || !fetched {
+		return sound
+	}
+
+	for _, fallback := range p.soundFallback {
+		if known, _ := p.knownSound(fallback); known {
+			return fallback
+		}
+	}
+
+	return sound
+}
@@ -0,0 +1,174 @@
+package pushover
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFakeSoundsServer returns a server listing a custom chime sound
+func newFakeSoundsServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sounds.json") {
+			fmt.Fprintln(w, `{"sounds":{"pushover":"Pushover (default)","siren":"Siren","chime":"My chime"},"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+			return
+		}
+
+		w.Header().Set("X-Limit-App-Limit", "7500")
+		w.Header().Set("X-Limit-App-Remaining", "6000")
+		w.Header().Set("X-Limit-App-Reset", "1393653600")
+		fmt.Fprintln(w, `{"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+}
+
+// TestGetSounds tests the built-in and custom sounds
+func TestGetSounds(t *testing.T) {
+	ts := newFakeSoundsServer()
+	defer ts.Close()
+
+	APIEndpoint = ts.URL
+	got, err := fakePushover.GetSounds()
+	if err != nil {
+		t.Fatalf("expected no error, got %q", err)
+	}
+
+	expected := &Sounds{
+		BuiltIn: map[string]string{"pushover": "Pushover (default)", "siren": "Siren"},
+		Custom:  map[string]string{"chime": "My chime"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+// TestStrictSoundValidation tests the sounds accepted by the strict
+// validation
+func TestStrictSoundValidation(t *testing.T) {
+	ts := newFakeSoundsServer()
+	defer ts.Close()
+
+	tt := []struct {
+		name    string
+		opts    []Option
+		fetched bool
+		sound   string
+		err     error
+	}{
+		{"not strict", nil, false, "chime", nil},
+		{"built-in sound", []Option{WithStrictValidation()}, false, SoundSiren, nil},
+		{"custom sound not fetched", []Option{WithStrictValidation()}, false, "chime", ErrUnknownSound},
+		{"custom sound fetched", []Option{WithStrictValidation()}, true, "chime", nil},
+		{"unknown sound", []Option{WithStrictValidation()}, true, "unknown", ErrUnknownSound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, tc.opts...)
+			if tc.fetched {
+				if _, err := p.GetSounds(); err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+			}
+
+			message := NewMessage("Hello")
+			message.Sound = tc.sound
+			if _, err := p.SendMessage(message, fakeRecipient); err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestSoundFallback tests the sound fallback chain
+func TestSoundFallback(t *testing.T) {
+	ts := newFakeSoundsServer()
+	defer ts.Close()
+
+	tt := []struct {
+		name     string
+		fallback []string
+		fetched  bool
+		sound    string
+		expected string
+	}{
+		{"sounds not fetched", []string{SoundSiren}, false, "missing", "missing"},
+		{"known custom sound", []string{SoundSiren}, true, "chime", "chime"},
+		{"missing custom sound", []string{SoundSiren}, true, "missing", SoundSiren},
+		{"first known fallback", []string{"other", "chime", SoundSiren}, true, "missing", "chime"},
+		{"no known fallback", []string{"other"}, true, "missing", "missing"},
+		{"no sound", []string{SoundSiren}, true, "", ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			APIEndpoint = ts.URL
+			p := New(fakePushover.token, WithSoundFallback(tc.fallback))
+			if tc.fetched {
+				if _, err := p.GetSounds(); err != nil {
+					t.Fatalf("expected no error, got %q", err)
+				}
+			}
+
+			message := NewMessage("Hello")
+			message.Sound = tc.sound
+			if got := p.prepareMessage(message).Sound; got != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// acceptGzipTransport sets the Accept-Encoding header, which disables the
+// transparent decompression of the default transport
+type acceptGzipTransport struct{}
+
+func (acceptGzipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// TestGetSoundsGzip tests the sounds listed with a gzip response
+func TestGetSoundsGzip(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("expected a gzip Accept-Encoding, got %q", r.Header.Get("Accept-Encoding"))
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		fmt.Fprintln(gz, `{"sounds":{"pushover":"Pushover (default)","chime":"My chime"},"status":1,"request":"e460545a8b333d0da2f3602aff3133d6"}`)
+	}))
+	defer ts.Close()
+	APIEndpoint = ts.URL
+
+	customHeaders := New(fakePushover.token)
+	customHeaders.client = &http.Client{Transport: acceptGzipTransport{}}
+
+	tt := []struct {
+		name string
+		app  *Pushover
+	}{
+		{"transparent decompression", New(fakePushover.token)},
+		{"custom Accept-Encoding", customHeaders},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.app.GetSounds()
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			if got.Custom["chime"] != "My chime" {
+				t.Fatalf("expected the chime custom sound, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package pushover
+
+import "net/http"
+
+// ParseSubscriptionRedirect returns the subscribed user key from the query of
+// the request redirected to the subscription success URL. The key is used as
+// a recipient with SendToSubscriber. The device name is only set if the
+// redirect has a pushover_device parameter, the subscribed users get all the
+// messages on all their devices otherwise.
+func ParseSubscriptionRedirect(r *http.Request) (userKey, deviceName string, err error) {
+	query := r.URL.Query()
+
+	userKey = query.Get("pushover_user_key")
+	if userKey == "" {
+		return "", "", ErrMissingSubscriptionKey
+	}
+
+	if !ValidUserKey(userKey) {
+		return "", "", ErrMalformedUserKey
+	}
+
+	deviceName = query.Get("pushover_device")
+	if deviceName != "" && !deviceNameRegexp.MatchString(deviceName) {
+		return "", "", ErrInvalidDeviceName
+	}
+
+	return userKey, deviceName, nil
+}
@@ -0,0 +1,36 @@
+package pushover
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseSubscriptionRedirect tests the subscribed user key parsing
+func TestParseSubscriptionRedirect(t *testing.T) {
+	tt := []struct {
+		name           string
+		url            string
+		expectedKey    string
+		expectedDevice string
+		err            error
+	}{
+		{"user key", "/subscribed?pushover_user_key=gznej3rKEVAvPUxu9vvNnqpmZpokzF", "gznej3rKEVAvPUxu9vvNnqpmZpokzF", "", nil},
+		{"user key and device", "/subscribed?pushover_user_key=gznej3rKEVAvPUxu9vvNnqpmZpokzF&pushover_device=iphone", "gznej3rKEVAvPUxu9vvNnqpmZpokzF", "iphone", nil},
+		{"missing user key", "/subscribed", "", "", ErrMissingSubscriptionKey},
+		{"malformed user key", "/subscribed?pushover_user_key=gznej3rKEVAvPUxu9", "", "", ErrMalformedUserKey},
+		{"invalid device", "/subscribed?pushover_user_key=gznej3rKEVAvPUxu9vvNnqpmZpokzF&pushover_device=a+b", "", "", ErrInvalidDeviceName},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			key, device, err := ParseSubscriptionRedirect(httptest.NewRequest("GET", tc.url, nil))
+			if err != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+
+			if key != tc.expectedKey || device != tc.expectedDevice {
+				t.Fatalf("expected %q and %q, got %q and %q", tc.expectedKey, tc.expectedDevice, key, device)
+			}
+		})
+	}
+}
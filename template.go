@@ -0,0 +1,94 @@
+package pushover
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Template builds messages from a title and a message templates, see the
+// text/template package for the syntax.
+type Template struct {
+	title   *template.Template
+	message *template.Template
+}
+
+// NewTemplate parses the title and the message templates.
+func NewTemplate(title, message string) (*Template, error) {
+	titleTemplate, err := template.New("title").Parse(title)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	messageTemplate, err := template.New("message").Parse(message)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	return &Template{title: titleTemplate, message: messageTemplate}, nil
+}
+
+// LoadTemplate parses a template file, the message is the content of the
+// file after an optional front matter with the title:
+//
+//	---
+//	title: Disk full on {{.Host}}
+//	---
+//	Only {{.Free}} left on {{.Disk}}.
+func LoadTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	title, message, err := parseFrontMatter(newlineReplacer.Replace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTemplate(title, message)
+}
+
+// parseFrontMatter splits the title of the front matter and the message.
+func parseFrontMatter(s string) (title, message string, err error) {
+	if !strings.HasPrefix(s, "---\n") {
+		return "", strings.TrimRight(s, "\n"), nil
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines[1:] {
+		if line == "---" {
+			message = strings.Join(lines[i+2:], "\n")
+			return title, strings.TrimRight(message, "\n"), nil
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != "title" {
+			return "", "", fmt.Errorf("%w: unknown front matter line %q", ErrInvalidTemplate, line)
+		}
+		title = strings.TrimSpace(parts[1])
+	}
+
+	return "", "", fmt.Errorf("%w: unterminated front matter", ErrInvalidTemplate)
+}
+
+// Render returns a new message with the title and the message rendered with
+// the given data.
+func (t *Template) Render(data interface{}) (*Message, error) {
+	var title, message bytes.Buffer
+	if err := t.title.Execute(&title, data); err != nil {
+		return nil, err
+	}
+
+	if err := t.message.Execute(&message, data); err != nil {
+		return nil, err
+	}
+
+	return NewMessageWithTitle(message.String(), title.String()), nil
+}
@@ -0,0 +1,91 @@
+package pushover
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadTemplate tests the templates loaded from a file and rendered
+func TestLoadTemplate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pushover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := struct{ Host, Free string }{"web-1", "2GB"}
+
+	tt := []struct {
+		name            string
+		content         string
+		expectedTitle   string
+		expectedMessage string
+		expectedErr     error
+	}{
+		{
+			name:            "front matter",
+			content:         "---\ntitle: Disk full on {{.Host}}\n---\nOnly {{.Free}} left\non {{.Host}}\n",
+			expectedTitle:   "Disk full on web-1",
+			expectedMessage: "Only 2GB left\non web-1",
+		},
+		{
+			name:            "windows line endings",
+			content:         "---\r\ntitle: Disk full\r\n---\r\nOnly {{.Free}} left\r\n",
+			expectedTitle:   "Disk full",
+			expectedMessage: "Only 2GB left",
+		},
+		{
+			name:            "without front matter",
+			content:         "Only {{.Free}} left\n",
+			expectedMessage: "Only 2GB left",
+		},
+		{
+			name:        "unknown front matter key",
+			content:     "---\npriority: 1\n---\nOnly {{.Free}} left\n",
+			expectedErr: ErrInvalidTemplate,
+		},
+		{
+			name:        "unterminated front matter",
+			content:     "---\ntitle: Disk full\nOnly {{.Free}} left\n",
+			expectedErr: ErrInvalidTemplate,
+		},
+		{
+			name:        "invalid template",
+			content:     "Only {{.Free left\n",
+			expectedErr: ErrInvalidTemplate,
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".tmpl")
+			if err := os.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			tmpl, err := LoadTemplate(path)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected %v, got %v", tc.expectedErr, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			message, err := tmpl.Render(data)
+			if err != nil {
+				t.Fatalf("expected no error, got %q", err)
+			}
+
+			if message.Title != tc.expectedTitle || message.Message != tc.expectedMessage {
+				t.Fatalf("expected %q %q, got %q %q", tc.expectedTitle, tc.expectedMessage, message.Title, message.Message)
+			}
+		})
+	}
+
+	if _, err := LoadTemplate(filepath.Join(dir, "missing.tmpl")); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
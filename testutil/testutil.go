@@ -0,0 +1,69 @@
+// Package testutil provides fake pushover notifiers to be used in tests.
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gregdel/pushover"
+)
+
+// NopNotifier is a notifier accepting every message without sending it.
+type NopNotifier struct{}
+
+// SendMessageContext returns a successful response.
+func (NopNotifier) SendMessageContext(ctx context.Context, message *pushover.Message, recipient *pushover.Recipient) (*pushover.Response, error) {
+	return &pushover.Response{Status: 1}, nil
+}
+
+// Notification represents a message sent to a RecordingNotifier.
+type Notification struct {
+	Message   *pushover.Message
+	Recipient *pushover.Recipient
+}
+
+// RecordingNotifier is a notifier keeping the messages sent, it's safe for
+// concurrent use.
+type RecordingNotifier struct {
+	// Err is returned by every send if set.
+	Err error
+
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+// SendMessageContext records the message and returns a successful response,
+// or Err if set.
+func (n *RecordingNotifier) SendMessageContext(ctx context.Context, message *pushover.Message, recipient *pushover.Recipient) (*pushover.Response, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.notifications = append(n.notifications, Notification{Message: message, Recipient: recipient})
+	if n.Err != nil {
+		return nil, n.Err
+	}
+
+	return &pushover.Response{Status: 1}, nil
+}
+
+// Notifications returns a copy of the recorded notifications in the sending
+// order.
+func (n *RecordingNotifier) Notifications() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]Notification(nil), n.notifications...)
+}
+
+// Reset removes the recorded notifications.
+func (n *RecordingNotifier) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.notifications = nil
+}
+
+var (
+	_ pushover.Notifier = NopNotifier{}
+	_ pushover.Notifier = (*RecordingNotifier)(nil)
+)
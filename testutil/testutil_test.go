@@ -0,0 +1,40 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gregdel/pushover"
+)
+
+// TestRecordingNotifier tests the recorded notifications
+func TestRecordingNotifier(t *testing.T) {
+	recipient := pushover.NewRecipient("gznej3rKEVAvPUxu9vvNnqpmZpokzF")
+	n := &RecordingNotifier{}
+
+	for _, text := range []string{"first", "second"} {
+		if _, err := n.SendMessageContext(context.Background(), pushover.NewMessage(text), recipient); err != nil {
+			t.Fatalf("expected no error, got %q", err)
+		}
+	}
+
+	notifications := n.Notifications()
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(notifications))
+	}
+
+	if notifications[1].Message.Message != "second" || notifications[1].Recipient != recipient {
+		t.Fatalf("unexpected notification %+v", notifications[1])
+	}
+
+	n.Err = errors.New("send failed")
+	if _, err := n.SendMessageContext(context.Background(), pushover.NewMessage("third"), recipient); err != n.Err {
+		t.Fatalf("expected %q, got %q", n.Err, err)
+	}
+
+	n.Reset()
+	if len(n.Notifications()) != 0 {
+		t.Fatalf("expected no notification after reset")
+	}
+}